@@ -0,0 +1,79 @@
+package grpclb
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+/*
+NewFromAddress creates a load balancer whose connections are dialed to target
+with grpc.NewClient, using the dial options set with WithDialOptions and
+WithCompressor. It is New with a factory built from the target; the other
+arguments and options have the same meaning.
+*/
+func NewFromAddress(size uint32, minRetryIntervalSeconds uint32, target string, logger func(msg string), opts ...Option) (LB, error) {
+	if target == "" {
+		return nil, errors.New("target can't be empty")
+	}
+
+	unset := func() (*grpc.ClientConn, error) {
+		return nil, errors.New("no target to dial")
+	}
+
+	return New(size, minRetryIntervalSeconds, unset, logger, append(append([]Option(nil), opts...), dialTarget(target))...)
+}
+
+/*
+dialTarget makes the pool dial target with its dial options. It is applied
+after the other options, so the factory sees all of them.
+*/
+func dialTarget(target string) Option {
+	return func(o *lb) {
+		o.target = target
+		o.factory = func() (*grpc.ClientConn, error) {
+			return grpc.NewClient(target, o.dialOptions...)
+		}
+	}
+}
+
+/*
+WithDialOptions adds dial options used for every connection of a pool created
+with NewFromAddress, such as transport credentials. New returns an error if
+they are used with a custom factory, which dials on its own.
+*/
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *lb) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+/*
+WithCompressor makes every RPC on the connections of a pool created with
+NewFromAddress compress its messages with the named compressor by default, for
+example "gzip" once google.golang.org/grpc/encoding/gzip is imported. New
+returns an error if no compressor of that name is registered.
+*/
+func WithCompressor(name string) Option {
+	return func(o *lb) {
+		o.compressor = name
+		o.dialOptions = append(o.dialOptions, grpc.WithDefaultCallOptions(grpc.UseCompressor(name)))
+	}
+}
+
+/*
+checkDialOptions validates the dial options against the way the pool dials.
+*/
+func (o *lb) checkDialOptions() error {
+	if len(o.dialOptions) > 0 && o.target == "" {
+		return errors.New("dial options require NewFromAddress")
+	}
+
+	if o.compressor != "" && encoding.GetCompressor(o.compressor) == nil {
+		return fmt.Errorf("compressor %q is not registered", o.compressor)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package grpclb
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/encoding/gzip"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestNewFromAddressUsesCompressor(t *testing.T) {
+	ts := startServer(t)
+	var compressor string
+	intercept := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoke grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		for _, opt := range opts {
+			if c, ok := opt.(grpc.CompressorCallOption); ok {
+				compressor = c.CompressorType
+			}
+		}
+
+		return invoke(ctx, method, req, reply, cc, opts...)
+	}
+
+	l, err := NewFromAddress(2, 1, "passthrough:///bufconn", nil,
+		WithDialOptions(
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return ts.listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUnaryInterceptor(intercept),
+		),
+		WithCompressor("gzip"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if _, err := healthpb.NewHealthClient(l.Get()).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	if compressor != "gzip" {
+		t.Errorf("RPC used compressor %q, want gzip", compressor)
+	}
+}
+
+func TestNewFromAddressRejectsMisconfiguration(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		target string
+		opts   []Option
+		want   string
+	}{
+		{"empty target", "", nil, "target can't be empty"},
+		{"unregistered compressor", "passthrough:///bufconn", []Option{WithCompressor("snappy")}, `"snappy" is not registered`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewFromAddress(1, 1, c.target, nil, c.opts...)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("got error %v, want one containing %q", err, c.want)
+			}
+		})
+	}
+}
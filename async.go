@@ -0,0 +1,80 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+WithAsyncReset moves automatic resets off the request path. When Get finds the
+connections unhealthy it starts a reset in a background goroutine and keeps
+selecting among the existing, possibly unhealthy, connections in the meantime.
+The replacements are dialed without holding the pool's mutex and swapped in
+once all of them have been dialed, so a slow factory no longer stalls callers.
+Only one background reset runs at a time, and ForceReset waits for a running
+one to complete rather than starting another.
+*/
+func WithAsyncReset() Option {
+	return func(o *lb) {
+		o.asyncReset = true
+	}
+}
+
+/*
+startReset starts a background reset, unless one is already running. The
+redialers are captured here, under the mutex, so the background dials see the
+factory and per connection settings of this moment. The caller must hold the
+mutex.
+*/
+func (o *lb) startReset() {
+	if o.resetCall != nil {
+		return
+	}
+
+	redials := make([]func() (*grpc.ClientConn, error), o.size)
+	for i := range redials {
+		redials[i] = o.redialer(uint32(i))
+	}
+
+	go o.resetAsync(o.beginReset(ResetAuto), redials)
+}
+
+/*
+resetAsync dials a replacement connection with each of redials without holding
+the mutex and then swaps them into the pool in a single step, completing call.
+If a dial fails, the connections dialed so far are closed and the pool keeps
+its current ones.
+*/
+func (o *lb) resetAsync(call *resetCall, redials []func() (*grpc.ClientConn, error)) {
+	conns := make([]*grpc.ClientConn, len(redials))
+	var dialErr error
+	for i, redial := range redials {
+		if conns[i], dialErr = redial(); dialErr != nil {
+			o.closeAll(conns)
+			break
+		}
+	}
+
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		if dialErr == nil {
+			o.closeAll(conns)
+		}
+		o.endReset(call, ErrClosed)
+		return
+	}
+
+	if dialErr != nil {
+		o.endReset(call, dialErr)
+		o.lastFailedReset = time.Now().UTC()
+		o.log("Failed to reset connections: " + dialErr.Error())
+		return
+	}
+
+	o.replaceAll(conns)
+	o.endReset(call, nil)
+	o.lastSuccessfulReset = time.Now().UTC()
+}
@@ -0,0 +1,107 @@
+package grpclb
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestAsyncResetDoesNotBlockGet(t *testing.T) {
+	ts := startServer(t)
+	dial := ts.factory()
+	gate := make(chan struct{})
+
+	var calls int32
+	factory := func() (*grpc.ClientConn, error) {
+		if atomic.AddInt32(&calls, 1) > 2 {
+			<-gate
+		}
+
+		return dial()
+	}
+
+	l, err := New(2, 1, factory, nil, WithAsyncReset(), WithIdleGrace(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+
+	old := []*grpc.ClientConn{o.Get(), o.Get()}
+	for i := 0; i < 3; i++ {
+		o.allowReset()
+		got := make(chan *grpc.ClientConn, 1)
+		go func() {
+			got <- o.Get()
+		}()
+
+		select {
+		case conn := <-got:
+			if conn != old[0] && conn != old[1] {
+				t.Fatal("Get returned a connection that is not part of the pool")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Get blocked on the background reset")
+		}
+	}
+
+	eventually(t, "the background reset to dial", func() bool {
+		return atomic.LoadInt32(&calls) == 3
+	})
+
+	close(gate)
+	eventually(t, "the reset to complete", func() bool {
+		o.mutex.Lock()
+		defer o.unlock()
+		return o.resetCall == nil
+	})
+
+	o.mutex.Lock()
+	for _, s := range o.slots {
+		if s.conn == old[0] || s.conn == old[1] {
+			t.Error("the background reset did not replace every connection")
+		}
+	}
+	o.unlock()
+
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Errorf("factory called %d times, want 4 for a single background reset", n)
+	}
+}
+
+func TestGetResultReportsOnlyPaidResets(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"synchronous reset", nil, true},
+		{"background reset", []Option{WithAsyncReset()}, false},
+		{"background reset waited for", []Option{WithAsyncReset(), WithResetWait(time.Second)}, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ts := startServer(t)
+			o := newTestLB(t, ts, 1, append(c.opts, WithIdleGrace(0))...)
+
+			if r, err := o.GetResult(); err != nil || r.Reset {
+				t.Fatalf("first GetResult: Reset %v, error %v, want no reset", r.Reset, err)
+			}
+
+			o.allowReset()
+			r, err := o.GetResult()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r.Reset != c.want {
+				t.Errorf("Reset = %v, want %v", r.Reset, c.want)
+			}
+
+			eventually(t, "the reset to dial", func() bool {
+				return ts.dialCount() == 2
+			})
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package grpclb
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+CallOption configures a single Call.
+*/
+type CallOption func(*callConfig)
+
+/*
+callConfig holds the settings of a Call.
+*/
+type callConfig struct {
+	retries   uint32
+	hedge     time.Duration
+	retryable func(err error) bool
+}
+
+/*
+CallRetries makes Call retry a failed attempt up to n times, each time on a
+different connection than the previous attempt. By default Call does not
+retry.
+*/
+func CallRetries(n uint32) CallOption {
+	return func(c *callConfig) {
+		c.retries = n
+	}
+}
+
+/*
+CallHedge makes every attempt of Call hedged after delay, see Hedge, so fn must
+be safe to run twice.
+*/
+func CallHedge(delay time.Duration) CallOption {
+	return func(c *callConfig) {
+		c.hedge = delay
+	}
+}
+
+/*
+CallRetryIf sets the function deciding whether an error returned by fn is worth
+a retry and counts against the connection's health. Errors it rejects, such as
+application errors, end the call right away and are reported to the pool as
+successes, for hedged attempts too. By default only errors with status code
+Unavailable are retried.
+*/
+func CallRetryIf(retryable func(err error) bool) CallOption {
+	return func(c *callConfig) {
+		c.retryable = retryable
+	}
+}
+
+/*
+unavailable is the default retry predicate of Call.
+*/
+func unavailable(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+/*
+Call runs fn on a connection of the pool and takes care of selection, retries
+on other connections, hedging and reporting the outcome of every attempt for
+outlier detection, as configured by opts. It stops once ctx is done, so the
+deadline of ctx bounds the call including its retries. The error of the last
+attempt is returned, or the context error if ctx ended the call. With
+WithShadow, fn is mirrored to the shadow connection once per sampled call.
+*/
+func (o *lb) Call(ctx context.Context, fn func(ctx context.Context, conn *grpc.ClientConn) error, opts ...CallOption) error {
+	cfg := callConfig{retryable: unavailable}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	o.mirror(ctx, fn)
+
+	report := func(conn *grpc.ClientConn, err error) {
+		if err != nil && !cfg.retryable(err) {
+			o.Report(conn, nil)
+		} else {
+			o.Report(conn, err)
+		}
+	}
+
+	var (
+		prev *grpc.ClientConn
+		err  error
+	)
+
+	for attempt := uint32(0); attempt <= cfg.retries; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		var conn *grpc.ClientConn
+		if prev == nil {
+			conn = o.Get()
+		} else {
+			conn = o.GetExcluding(prev)
+		}
+
+		if conn == nil {
+			return errNoConn
+		}
+
+		if cfg.hedge > 0 {
+			r := o.hedge(ctx, cfg.hedge, conn, func(ctx context.Context, conn *grpc.ClientConn) (interface{}, error) {
+				return nil, fn(ctx, conn)
+			}, report)
+			conn, err = r.conn, r.err
+		} else {
+			err = fn(ctx, conn)
+			report(conn, err)
+		}
+
+		prev = conn
+
+		if err == nil || !cfg.retryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,68 @@
+package grpclb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCallRetriesOnAnotherConn(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []CallOption
+	}{
+		{"plain", nil},
+		{"hedged", []CallOption{CallHedge(time.Hour)}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ts := startServer(t)
+			o := newTestLB(t, ts, 2)
+			connectAll(t, o)
+			if err := o.Pin(0); err != nil {
+				t.Fatal(err)
+			}
+
+			var used []*grpc.ClientConn
+			err := o.Call(context.Background(), func(ctx context.Context, conn *grpc.ClientConn) error {
+				used = append(used, conn)
+				if len(used) == 1 {
+					return status.Error(codes.Unavailable, "first attempt fails")
+				}
+				return nil
+			}, append(c.opts, CallRetries(1))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(used) != 2 || used[0] == used[1] {
+				t.Fatal("the retry ran on the connection of the failed attempt")
+			}
+
+			stats := o.Stats()
+			if stats.Failures != 1 || stats.Successes != 1 {
+				t.Errorf("reported %d failures and %d successes, want 1 and 1", stats.Failures, stats.Successes)
+			}
+		})
+	}
+}
+
+func TestHedgedCallReportsRejectedErrorsAsSuccess(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 1)
+
+	appErr := status.Error(codes.InvalidArgument, "bad request")
+	err := o.Call(context.Background(), func(context.Context, *grpc.ClientConn) error {
+		return appErr
+	}, CallHedge(time.Hour), CallRetries(2))
+	if err != appErr {
+		t.Fatalf("Call: got error %v, want the application error", err)
+	}
+
+	if stats := o.Stats(); stats.Failures != 0 || stats.Successes != 1 {
+		t.Errorf("reported %d failures and %d successes, want the application error as 1 success", stats.Failures, stats.Successes)
+	}
+}
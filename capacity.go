@@ -0,0 +1,65 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+WithCapacityAlert calls cb with the fraction of the pool in rotation whenever
+that fraction drops below threshold, and again once it is back at or above it.
+A connection is out of rotation while it fails to connect, from entering
+TransientFailure until it is Ready or Idle again, or while outlier detection
+ejects it; connections that are idle or connecting for the first time count as
+in rotation. The fraction is recomputed on connectivity changes and, with
+outlier detection, on Report. cb runs without the pool's mutex held.
+*/
+func WithCapacityAlert(threshold float64, cb func(readyFraction float64)) Option {
+	return func(o *lb) {
+		o.capacityThreshold = threshold
+		o.onCapacity = cb
+	}
+}
+
+/*
+trackFailing records whether the slot's connection is failing to connect,
+which keeps it out of rotation for the capacity alert through the Connecting
+phases of its reconnect attempts. The caller must hold the mutex.
+*/
+func trackFailing(s *slot, state connectivity.State) {
+	switch state {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		s.failing = true
+	case connectivity.Ready, connectivity.Idle:
+		s.failing = false
+	}
+}
+
+/*
+checkCapacity computes the fraction of the pool in rotation and queues the
+capacity alert if it crossed the threshold since the last check. The caller
+must hold the mutex.
+*/
+func (o *lb) checkCapacity() {
+	if o.onCapacity == nil || o.closed {
+		return
+	}
+
+	now := time.Now()
+	in := 0
+	for _, s := range o.slots {
+		if !s.failing && !o.ejected(s, now) {
+			in++
+		}
+	}
+
+	fraction := float64(in) / float64(len(o.slots))
+	if degraded := fraction < o.capacityThreshold; degraded != o.degraded {
+		o.degraded = degraded
+		onCapacity := o.onCapacity
+		o.pending = append(o.pending, func() {
+			onCapacity(fraction)
+		})
+	}
+}
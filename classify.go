@@ -0,0 +1,35 @@
+package grpclb
+
+import "fmt"
+
+/*
+WithErrorClassifier sets the function deciding whether an error returned while
+dialing connections is retryable. A reset that fails with an error classified
+as fatal, such as an invalid target, stops automatic resets and per connection
+recovery: selection then fails with that error right away instead of redialing
+on every retry interval. A successful ForceReset, Swap or Refresh clears the
+condition. By default every error is retryable.
+*/
+func WithErrorClassifier(retryable func(err error) bool) Option {
+	return func(o *lb) {
+		o.retryable = retryable
+	}
+}
+
+/*
+classify records the outcome of a reset or redial for the error classifier:
+a fatal err stops automatic recovery, a nil err resumes it. The caller must
+hold the mutex.
+*/
+func (o *lb) classify(err error) {
+	switch {
+	case err == nil:
+		o.fatal = nil
+	case o.retryable != nil && err != ErrClosed && !o.retryable(err):
+		if o.fatal == nil {
+			o.log("Stopping automatic resets after a fatal error: " + err.Error())
+		}
+
+		o.fatal = fmt.Errorf("automatic resets stopped after a fatal error: %w", err)
+	}
+}
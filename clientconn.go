@@ -0,0 +1,100 @@
+package grpclb
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// indexKey is the context key under which the adapter stores the slot index.
+type indexKey struct{}
+
+/*
+WithIndexMetadata makes the ClientConn adapter attach the index of the slot
+each RPC was sent on to its outgoing metadata under headerKey, so servers can
+correlate requests with client connections. gRPC lowercases the key. By
+default no metadata is added; the index is always available to client
+interceptors through IndexFromContext.
+*/
+func WithIndexMetadata(headerKey string) Option {
+	return func(o *lb) {
+		o.indexMetadata = headerKey
+	}
+}
+
+/*
+IndexFromContext returns the index of the slot an RPC made through the
+ClientConn adapter was sent on. Client interceptors receive the context
+carrying it. The second result is false for contexts that did not pass through
+the adapter.
+*/
+func IndexFromContext(ctx context.Context) (uint32, bool) {
+	index, ok := ctx.Value(indexKey{}).(uint32)
+	return index, ok
+}
+
+/*
+clientConn adapts the load balancer to grpc.ClientConnInterface.
+*/
+type clientConn struct {
+	o *lb
+}
+
+/*
+ClientConn returns the load balancer as a grpc.ClientConnInterface, for use
+with generated clients. Every RPC selects a connection the same way GetResult
+does and records the slot index in the context, see IndexFromContext and
+WithIndexMetadata. If no connection can be selected, the RPC fails with the
+selection error.
+*/
+func (o *lb) ClientConn() grpc.ClientConnInterface {
+	return clientConn{o: o}
+}
+
+/*
+Invoke implements grpc.ClientConnInterface.
+*/
+func (c clientConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	conn, ctx, err := c.o.selectFor(ctx)
+	if err != nil {
+		return err
+	}
+
+	return conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+/*
+NewStream implements grpc.ClientConnInterface.
+*/
+func (c clientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	conn, ctx, err := c.o.selectFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.NewStream(ctx, desc, method, opts...)
+}
+
+/*
+selectFor selects a connection for an RPC and returns it with ctx carrying
+the slot index.
+*/
+func (o *lb) selectFor(ctx context.Context) (*grpc.ClientConn, context.Context, error) {
+	r, err := o.GetResult()
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	if r.Conn == nil {
+		return nil, ctx, errNoConn
+	}
+
+	ctx = context.WithValue(ctx, indexKey{}, r.Index)
+	if o.indexMetadata != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, o.indexMetadata, strconv.FormatUint(uint64(r.Index), 10))
+	}
+
+	return r.Conn, ctx, nil
+}
@@ -0,0 +1,81 @@
+package grpclb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+TestConcurrentUse hammers a pool from many goroutines with selections,
+resizes and recycles, and closes it while they keep going. It is meant to be
+run with -race.
+*/
+func TestConcurrentUse(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{
+		{"round robin", nil},
+		{"resize grace", []Option{WithResizeGrace(10 * time.Millisecond)}},
+		{"per slot recovery", []Option{WithPerSlotRecovery(), WithIdleGrace(0)}},
+		{"async reset", []Option{WithAsyncReset(), WithIdleGrace(0)}},
+		{"load aware", []Option{WithStrategy(WeightedLeastConnections), WithMaxInFlight(4)}},
+		{"state watcher", []Option{WithReadySet(), WithSlowStart(50 * time.Millisecond), WithGoAwayRefresh()}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ts := startServer(t)
+			o := newTestLB(t, ts, 4, c.opts...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			var wg sync.WaitGroup
+			run := func(fn func(i int)) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; ctx.Err() == nil; i++ {
+						fn(i)
+					}
+				}()
+			}
+
+			for i := 0; i < 8; i++ {
+				run(func(int) {
+					if conn := o.Get(); conn != nil {
+						conn.Connect()
+					}
+				})
+			}
+
+			run(func(int) {
+				if pc, ok := o.TryAcquire(); ok {
+					pc.Done()
+				}
+			})
+			run(func(i int) {
+				_ = o.Resize(uint32(1 + i%6))
+			})
+			run(func(i int) {
+				_ = o.RecycleConn(uint32(i % 4))
+				time.Sleep(time.Millisecond)
+			})
+			run(func(int) {
+				_ = o.Stats()
+				_ = o.UnhealthyIndices()
+			})
+
+			time.Sleep(200 * time.Millisecond)
+			if err := o.Close(); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+
+			wg.Wait()
+			if conn := o.Get(); conn != nil {
+				t.Error("Get returned a connection after Close")
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package grpclb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+Config is a declarative, JSON serializable description of a pool, for example
+loaded from a configuration file. Every field maps to the argument of New or
+the option of the same name; zero values leave the respective default in place.
+Strategy is "round_robin", "weighted_random", "weighted_least_connections" or
+"least_bytes_in_flight", TieBreaker is "round_robin", "random" or
+"lowest_index", ShrinkPolicy is "highest_index", "least_used" or
+"least_healthy" and UsableStates lists connectivity state names such as "READY"
+or "IDLE". AutoReset defaults to true, ResetHistorySize to
+DefaultResetHistorySize and IdleGrace to DefaultIdleGrace when omitted.
+*/
+type Config struct {
+	Size                    uint32              `json:"size"`
+	MinRetryIntervalSeconds uint32              `json:"minRetryIntervalSeconds"`
+	Name                    string              `json:"name,omitempty"`
+	Stride                  uint32              `json:"stride,omitempty"`
+	MaxSize                 uint32              `json:"maxSize,omitempty"`
+	MaxInFlight             uint32              `json:"maxInFlight,omitempty"`
+	MaxOverflow             uint32              `json:"maxOverflow,omitempty"`
+	OverflowIdleTimeout     Duration            `json:"overflowIdleTimeout,omitempty"`
+	WaitForReady            bool                `json:"waitForReady,omitempty"`
+	AutoReset               *bool               `json:"autoReset,omitempty"`
+	AsyncReset              bool                `json:"asyncReset,omitempty"`
+	ResetWait               Duration            `json:"resetWait,omitempty"`
+	PerSlotRecovery         bool                `json:"perSlotRecovery,omitempty"`
+	MaxConcurrentResets     uint32              `json:"maxConcurrentResets,omitempty"`
+	GoAwayRefresh           bool                `json:"goAwayRefresh,omitempty"`
+	ResetBatch              uint32              `json:"resetBatch,omitempty"`
+	ResetBatchDelay         Duration            `json:"resetBatchDelay,omitempty"`
+	ResetDeadline           Duration            `json:"resetDeadline,omitempty"`
+	ResetHistorySize        *int                `json:"resetHistorySize,omitempty"`
+	UsableStates            []string            `json:"usableStates,omitempty"`
+	ResizeGrace             Duration            `json:"resizeGrace,omitempty"`
+	ShrinkPolicy            string              `json:"shrinkPolicy,omitempty"`
+	RecoveryGrace           Duration            `json:"recoveryGrace,omitempty"`
+	IdleGrace               *Duration           `json:"idleGrace,omitempty"`
+	PostResetCooldown       Duration            `json:"postResetCooldown,omitempty"`
+	SlowStart               Duration            `json:"slowStart,omitempty"`
+	PoolMaxAge              Duration            `json:"poolMaxAge,omitempty"`
+	DrainTimeout            Duration            `json:"drainTimeout,omitempty"`
+	StaggeredDial           Duration            `json:"staggeredDial,omitempty"`
+	FailFast                Duration            `json:"failFast,omitempty"`
+	LatencyProbeInterval    Duration            `json:"latencyProbeInterval,omitempty"`
+	ORCA                    bool                `json:"orca,omitempty"`
+	ReadySet                bool                `json:"readySet,omitempty"`
+	StateDurations          bool                `json:"stateDurations,omitempty"`
+	PreferRecovered         Duration            `json:"preferRecovered,omitempty"`
+	ActiveWindow            uint32              `json:"activeWindow,omitempty"`
+	ActiveWindowRotate      Duration            `json:"activeWindowRotate,omitempty"`
+	Strategy                string              `json:"strategy,omitempty"`
+	TieBreaker              string              `json:"tieBreaker,omitempty"`
+	Weights                 []uint32            `json:"weights,omitempty"`
+	Priorities              []int               `json:"priorities,omitempty"`
+	PrioritySpillover       float64             `json:"prioritySpillover,omitempty"`
+	Groups                  []string            `json:"groups,omitempty"`
+	Tags                    []map[string]string `json:"tags,omitempty"`
+	SlotResetAttempts       []uint32            `json:"slotResetAttempts,omitempty"`
+	IndexMetadata           string              `json:"indexMetadata,omitempty"`
+	Outlier                 *OutlierSetting     `json:"outlier,omitempty"`
+}
+
+/*
+OutlierSetting is the serializable form of OutlierConfig.
+*/
+type OutlierSetting struct {
+	ConsecutiveFailures uint32   `json:"consecutiveFailures,omitempty"`
+	MinSuccessRate      float64  `json:"minSuccessRate,omitempty"`
+	MinRequests         uint64   `json:"minRequests,omitempty"`
+	BaseEjectionTime    Duration `json:"baseEjectionTime,omitempty"`
+	MaxEjectionTime     Duration `json:"maxEjectionTime,omitempty"`
+	MaxEjectionPercent  uint32   `json:"maxEjectionPercent,omitempty"`
+}
+
+/*
+Duration is a time.Duration that is written to JSON as a string such as "1.5s"
+and read from either such a string or a number of nanoseconds.
+*/
+type Duration time.Duration
+
+/*
+MarshalJSON implements json.Marshaler.
+*/
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+/*
+UnmarshalJSON implements json.Unmarshaler.
+*/
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case float64:
+		*d = Duration(v)
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %v", v, err)
+		}
+
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+
+	return nil
+}
+
+// strategyNames maps the strategy names used in Config to strategies.
+var strategyNames = map[string]Strategy{
+	"round_robin":                RoundRobin,
+	"weighted_random":            WeightedRandom,
+	"weighted_least_connections": WeightedLeastConnections,
+	"least_bytes_in_flight":      LeastBytesInFlight,
+}
+
+/*
+NewFromConfig creates a load balancer from cfg, like New does from its
+arguments and options. Settings that cannot be expressed in JSON, such as a
+validator or select middlewares, can be passed as additional options; they are
+applied after the ones derived from cfg. A config with unknown names or
+contradicting settings is rejected with an error describing the problem.
+*/
+func NewFromConfig(cfg Config, factory func() (*grpc.ClientConn, error), logger func(msg string), opts ...Option) (LB, error) {
+	configOpts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(cfg.Size, cfg.MinRetryIntervalSeconds, factory, logger, append(configOpts, opts...)...)
+}
+
+/*
+options translates the config into options. Names are checked here; whether
+the settings combine into a working configuration is checked by New, with the
+same rules as for options passed to it directly.
+*/
+func (cfg Config) options() ([]Option, error) {
+	autoReset := cfg.AutoReset == nil || *cfg.AutoReset
+
+	var opts []Option
+	if cfg.Name != "" {
+		opts = append(opts, WithName(cfg.Name))
+	}
+
+	if cfg.Stride != 0 {
+		opts = append(opts, WithStride(cfg.Stride))
+	}
+
+	if cfg.MaxSize != 0 {
+		opts = append(opts, WithMaxSize(cfg.MaxSize))
+	}
+
+	if cfg.MaxInFlight != 0 {
+		opts = append(opts, WithMaxInFlight(cfg.MaxInFlight))
+	}
+
+	if cfg.MaxOverflow != 0 || cfg.OverflowIdleTimeout != 0 {
+		opts = append(opts, WithOverflow(cfg.MaxOverflow, time.Duration(cfg.OverflowIdleTimeout)))
+	}
+
+	if cfg.WaitForReady {
+		opts = append(opts, WithWaitForReady())
+	}
+
+	if !autoReset {
+		opts = append(opts, WithAutoReset(false))
+	}
+
+	if cfg.AsyncReset {
+		opts = append(opts, WithAsyncReset())
+	}
+
+	if cfg.ResetWait != 0 {
+		opts = append(opts, WithResetWait(time.Duration(cfg.ResetWait)))
+	}
+
+	if cfg.PerSlotRecovery {
+		opts = append(opts, WithPerSlotRecovery())
+	}
+
+	if cfg.MaxConcurrentResets != 0 {
+		opts = append(opts, WithMaxConcurrentResets(cfg.MaxConcurrentResets))
+	}
+
+	if cfg.GoAwayRefresh {
+		opts = append(opts, WithGoAwayRefresh())
+	}
+
+	if cfg.ResetBatch != 0 || cfg.ResetBatchDelay != 0 {
+		opts = append(opts, WithResetBatch(cfg.ResetBatch, time.Duration(cfg.ResetBatchDelay)))
+	}
+
+	if cfg.ResetDeadline != 0 {
+		opts = append(opts, WithResetDeadline(time.Duration(cfg.ResetDeadline)))
+	}
+
+	if cfg.ResetHistorySize != nil {
+		opts = append(opts, WithResetHistorySize(*cfg.ResetHistorySize))
+	}
+
+	if cfg.UsableStates != nil {
+		states, err := parseStates(cfg.UsableStates)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, WithUsableStates(states...))
+	}
+
+	if cfg.ResizeGrace != 0 {
+		opts = append(opts, WithResizeGrace(time.Duration(cfg.ResizeGrace)))
+	}
+
+	if cfg.ShrinkPolicy != "" {
+		policy, ok := shrinkPolicyNames[cfg.ShrinkPolicy]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown shrink policy %q", cfg.ShrinkPolicy)
+		}
+
+		opts = append(opts, WithShrinkPolicy(policy))
+	}
+
+	if cfg.RecoveryGrace != 0 {
+		opts = append(opts, WithRecoveryGrace(time.Duration(cfg.RecoveryGrace)))
+	}
+
+	if cfg.IdleGrace != nil {
+		opts = append(opts, WithIdleGrace(time.Duration(*cfg.IdleGrace)))
+	}
+
+	if cfg.PostResetCooldown != 0 {
+		opts = append(opts, WithPostResetCooldown(time.Duration(cfg.PostResetCooldown)))
+	}
+
+	if cfg.SlowStart != 0 {
+		opts = append(opts, WithSlowStart(time.Duration(cfg.SlowStart)))
+	}
+
+	if cfg.PoolMaxAge != 0 {
+		opts = append(opts, WithPoolMaxAge(time.Duration(cfg.PoolMaxAge)))
+	}
+
+	if cfg.DrainTimeout != 0 {
+		opts = append(opts, WithDrainTimeout(time.Duration(cfg.DrainTimeout)))
+	}
+
+	if cfg.StaggeredDial != 0 {
+		opts = append(opts, WithStaggeredDial(time.Duration(cfg.StaggeredDial)))
+	}
+
+	if cfg.FailFast != 0 {
+		opts = append(opts, WithFailFast(time.Duration(cfg.FailFast)))
+	}
+
+	if cfg.LatencyProbeInterval != 0 {
+		opts = append(opts, WithLatencyAffinity(time.Duration(cfg.LatencyProbeInterval)))
+	}
+
+	if cfg.ORCA {
+		opts = append(opts, WithORCA())
+	}
+
+	if cfg.ReadySet {
+		opts = append(opts, WithReadySet())
+	}
+
+	if cfg.StateDurations {
+		opts = append(opts, WithStateDurations())
+	}
+
+	if cfg.PreferRecovered != 0 {
+		opts = append(opts, WithPreferRecovered(time.Duration(cfg.PreferRecovered)))
+	}
+
+	if cfg.ActiveWindow != 0 || cfg.ActiveWindowRotate != 0 {
+		opts = append(opts, WithActiveWindow(cfg.ActiveWindow, time.Duration(cfg.ActiveWindowRotate)))
+	}
+
+	if cfg.Strategy != "" {
+		strategy, ok := strategyNames[cfg.Strategy]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown strategy %q", cfg.Strategy)
+		}
+
+		opts = append(opts, WithStrategy(strategy))
+	}
+
+	if cfg.TieBreaker != "" {
+		tieBreaker, ok := tieBreakerNames[cfg.TieBreaker]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown tie breaker %q", cfg.TieBreaker)
+		}
+
+		opts = append(opts, WithTieBreaker(tieBreaker))
+	}
+
+	if cfg.Weights != nil {
+		opts = append(opts, WithWeights(cfg.Weights))
+	}
+
+	if cfg.Priorities != nil {
+		opts = append(opts, WithPriorities(cfg.Priorities))
+	}
+
+	if cfg.PrioritySpillover != 0 {
+		opts = append(opts, WithPrioritySpillover(cfg.PrioritySpillover))
+	}
+
+	if cfg.Groups != nil {
+		opts = append(opts, WithGroups(cfg.Groups))
+	}
+
+	if cfg.Tags != nil {
+		opts = append(opts, WithTags(cfg.Tags))
+	}
+
+	if cfg.SlotResetAttempts != nil {
+		opts = append(opts, WithSlotResetAttempts(cfg.SlotResetAttempts))
+	}
+
+	if cfg.IndexMetadata != "" {
+		opts = append(opts, WithIndexMetadata(cfg.IndexMetadata))
+	}
+
+	if cfg.Outlier != nil {
+		opts = append(opts, WithOutlierDetection(OutlierConfig{
+			ConsecutiveFailures: cfg.Outlier.ConsecutiveFailures,
+			MinSuccessRate:      cfg.Outlier.MinSuccessRate,
+			MinRequests:         cfg.Outlier.MinRequests,
+			BaseEjectionTime:    time.Duration(cfg.Outlier.BaseEjectionTime),
+			MaxEjectionTime:     time.Duration(cfg.Outlier.MaxEjectionTime),
+			MaxEjectionPercent:  cfg.Outlier.MaxEjectionPercent,
+		}))
+	}
+
+	return opts, nil
+}
+
+/*
+Config returns the effective configuration of the load balancer, reflecting
+its current size, weights, priorities, groups and tags. Weights are included if
+they were set explicitly or the strategy uses them, not the implicit weights of
+1 of the other strategies. Settings that have no serializable form, such as a
+validator, select middlewares, callbacks or a connection store, are not
+included; UsableStates lists the states the usable predicate accepts. The
+returned config is a copy and can be modified freely and passed to
+NewFromConfig to create an equivalent pool.
+*/
+func (o *lb) Config() Config {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	autoReset, historySize, idleGrace := o.autoReset, o.historySize, Duration(o.idleGrace)
+	cfg := Config{
+		Size:                    o.size,
+		MinRetryIntervalSeconds: o.minRetryIntervalSeconds,
+		Name:                    o.name,
+		Stride:                  o.stride,
+		MaxSize:                 o.maxSize,
+		MaxInFlight:             uint32(o.maxInFlight),
+		MaxOverflow:             o.maxOverflow,
+		OverflowIdleTimeout:     Duration(o.overflowIdleTimeout),
+		WaitForReady:            o.waitForReady,
+		AutoReset:               &autoReset,
+		AsyncReset:              o.asyncReset,
+		ResetWait:               Duration(o.resetWait),
+		PerSlotRecovery:         o.perSlotRecovery,
+		MaxConcurrentResets:     o.maxConcurrentResets,
+		GoAwayRefresh:           o.goAwayRefresh,
+		ResetBatch:              o.resetBatch,
+		ResetBatchDelay:         Duration(o.resetBatchDelay),
+		ResetDeadline:           Duration(o.resetDeadline),
+		ResetHistorySize:        &historySize,
+		ResizeGrace:             Duration(o.resizeGrace),
+		RecoveryGrace:           Duration(o.recoveryGrace),
+		IdleGrace:               &idleGrace,
+		PostResetCooldown:       Duration(o.cooldown),
+		SlowStart:               Duration(o.slowStart),
+		DrainTimeout:            Duration(o.drainTimeout),
+		PoolMaxAge:              Duration(o.poolMaxAge),
+		StaggeredDial:           Duration(o.dialSpread),
+		FailFast:                Duration(o.failFast),
+		ORCA:                    o.orca,
+		ReadySet:                o.readySet,
+		StateDurations:          o.stateDurations,
+		PreferRecovered:         Duration(o.preferRecovered),
+		ActiveWindow:            o.window,
+		ActiveWindowRotate:      Duration(o.windowRotate),
+		SlotResetAttempts:       append([]uint32(nil), o.resetAttempts...),
+		Priorities:              append([]int(nil), o.priorities...),
+		PrioritySpillover:       o.spillover,
+		Groups:                  append([]string(nil), o.groups...),
+		IndexMetadata:           o.indexMetadata,
+	}
+
+	if o.weightsSet || o.usesWeights() {
+		cfg.Weights = append([]uint32(nil), o.weights...)
+	}
+
+	if o.tags != nil {
+		cfg.Tags = make([]map[string]string, len(o.tags))
+		for i, t := range o.tags {
+			cfg.Tags[i] = copyTags(t)
+		}
+	}
+
+	if o.latencyAffinity {
+		cfg.LatencyProbeInterval = Duration(o.probeInterval)
+	}
+
+	for name, strategy := range strategyNames {
+		if strategy == o.strategy {
+			cfg.Strategy = name
+		}
+	}
+
+	for name, policy := range shrinkPolicyNames {
+		if policy == o.shrinkPolicy {
+			cfg.ShrinkPolicy = name
+		}
+	}
+
+	for name, tieBreaker := range tieBreakerNames {
+		if tieBreaker == o.tieBreaker {
+			cfg.TieBreaker = name
+		}
+	}
+
+	for _, state := range allStates {
+		if o.usable(state) {
+			cfg.UsableStates = append(cfg.UsableStates, state.String())
+		}
+	}
+
+	if o.outlier != nil {
+		cfg.Outlier = &OutlierSetting{
+			ConsecutiveFailures: o.outlier.ConsecutiveFailures,
+			MinSuccessRate:      o.outlier.MinSuccessRate,
+			MinRequests:         o.outlier.MinRequests,
+			BaseEjectionTime:    Duration(o.outlier.BaseEjectionTime),
+			MaxEjectionTime:     Duration(o.outlier.MaxEjectionTime),
+			MaxEjectionPercent:  o.outlier.MaxEjectionPercent,
+		}
+	}
+
+	return cfg
+}
+
+// allStates lists every connectivity state, in the order gRPC defines them.
+var allStates = []connectivity.State{
+	connectivity.Idle,
+	connectivity.Connecting,
+	connectivity.Ready,
+	connectivity.TransientFailure,
+	connectivity.Shutdown,
+}
+
+/*
+parseStates translates connectivity state names, as printed by
+connectivity.State, into states.
+*/
+func parseStates(names []string) ([]connectivity.State, error) {
+	states := make([]connectivity.State, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, state := range allStates {
+			if strings.EqualFold(name, state.String()) {
+				states = append(states, state)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("config: unknown connectivity state %q", name)
+		}
+	}
+
+	return states, nil
+}
@@ -0,0 +1,57 @@
+package grpclb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	ts := startServer(t)
+	for name := range strategyNames {
+		t.Run(name, func(t *testing.T) {
+			o := newTestLB(t, ts, 2, WithStrategy(strategyNames[name]))
+			cfg := o.Config()
+
+			data, err := json.Marshal(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded Config
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			clone, err := NewFromConfig(decoded, ts.factory(), nil)
+			if err != nil {
+				t.Fatalf("NewFromConfig(Config()): %v", err)
+			}
+			defer clone.Close()
+
+			if got := clone.Config(); !reflect.DeepEqual(got, cfg) {
+				t.Errorf("round trip changed the config:\n got %+v\nwant %+v", got, cfg)
+			}
+		})
+	}
+}
+
+func TestConfigWeights(t *testing.T) {
+	ts := startServer(t)
+	for _, c := range []struct {
+		name string
+		opts []Option
+		want []uint32
+	}{
+		{"implicit weights are omitted", []Option{WithStrategy(LeastBytesInFlight)}, nil},
+		{"weighted strategies report their weights", []Option{WithStrategy(WeightedRandom)}, []uint32{1, 1}},
+		{"explicit weights are kept", []Option{WithWeights([]uint32{2, 3})}, []uint32{2, 3}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			o := newTestLB(t, ts, 2, c.opts...)
+			if got := o.Config().Weights; !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Weights = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+WithPostResetCooldown deprioritizes connections created within the last d, by
+a reset or otherwise, until they have been Ready, so that selection does not
+hand out a connection that is still connecting right after replacing it. They
+are only picked when no other connection is accepted.
+*/
+func WithPostResetCooldown(d time.Duration) Option {
+	return func(o *lb) {
+		o.cooldown = d
+	}
+}
+
+/*
+settled reports whether the slot's connection is past the post reset cooldown:
+it has been Ready since it was created or it was created long enough ago. The
+caller must hold the mutex.
+*/
+func (o *lb) settled(s *slot) bool {
+	if s.proven || time.Since(s.createdAt) >= o.cooldown {
+		return true
+	}
+
+	s.proven = s.conn.GetState() == connectivity.Ready
+	return s.proven
+}
@@ -0,0 +1,134 @@
+package grpclb
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+ResetCoordinator is a reconnect budget shared by several load balancers, for
+example pools to the same backend cluster, so that a cluster restart does not
+make every pool redial all of its connections at once. Every automatic reset
+spends one token per connection it redials; tokens refill at a fixed rate up
+to a burst. A pool whose reset is not admitted keeps its current connections
+and tries again on a later selection. Resets of whole pools are also
+coalesced: only one of them runs at a time among the pools sharing the
+coordinator, and the others are deferred the same way until it completed, so
+the pools redial one after another instead of all at once. ForceReset,
+RecycleConn and DrainConn are not subject to the coordinator. A coordinator is
+safe for concurrent use.
+*/
+type ResetCoordinator struct {
+	mutex     sync.Mutex
+	rate      float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+	resetting bool
+}
+
+/*
+NewResetCoordinator creates a coordinator allowing perSecond connections to be
+redialed per second on average, with bursts of up to burst connections. A
+reset of more connections than burst is admitted once the budget is full.
+*/
+func NewResetCoordinator(perSecond float64, burst uint32) *ResetCoordinator {
+	return &ResetCoordinator{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+/*
+WithResetCoordinator subjects the automatic resets of the load balancer to the
+budget of coordinator and coalesces them with those of the other pools sharing
+it.
+*/
+func WithResetCoordinator(coordinator *ResetCoordinator) Option {
+	return func(o *lb) {
+		o.coordinator = coordinator
+	}
+}
+
+/*
+allow reports whether n connections may be redialed now, spending their tokens
+if so.
+*/
+func (c *ResetCoordinator) allow(n uint32) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.spend(n)
+}
+
+/*
+spend refills the budget and spends n tokens if it allows that. The caller
+must hold the coordinator's mutex.
+*/
+func (c *ResetCoordinator) spend(n uint32) bool {
+	now := time.Now()
+	c.tokens += now.Sub(c.last).Seconds() * c.rate
+	if c.tokens > c.burst {
+		c.tokens = c.burst
+	}
+	c.last = now
+
+	need := float64(n)
+	if need > c.burst {
+		need = c.burst
+	}
+
+	if c.tokens < need {
+		return false
+	}
+
+	c.tokens -= float64(n)
+	return true
+}
+
+/*
+begin reports whether a reset of a whole pool of n connections may start now,
+which is the case when no other one is in flight and the budget allows it. If
+so, the reset is in flight until end is called.
+*/
+func (c *ResetCoordinator) begin(n uint32) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.resetting || !c.spend(n) {
+		return false
+	}
+
+	c.resetting = true
+	return true
+}
+
+/*
+end records that the reset admitted by begin completed.
+*/
+func (c *ResetCoordinator) end() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.resetting = false
+}
+
+/*
+admitReset reports whether an automatic reset of n connections may start. The
+caller must hold the mutex.
+*/
+func (o *lb) admitReset(n uint32) bool {
+	return o.coordinator == nil || o.coordinator.allow(n)
+}
+
+/*
+admitPoolReset reports whether an automatic reset of the whole pool may start.
+If it is admitted by the coordinator, the reset has to be started with the
+ResetAuto trigger, whose completion ends it at the coordinator. The caller must
+hold the mutex.
+*/
+func (o *lb) admitPoolReset() bool {
+	return o.coordinator == nil || o.coordinator.begin(o.size)
+}
@@ -0,0 +1,59 @@
+package grpclb
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestResetCoordinatorCoalescesPoolResets(t *testing.T) {
+	coordinator := NewResetCoordinator(1000, 10)
+	gate := make(chan struct{})
+
+	first := startServer(t)
+	var firstCalls int32
+	a, err := New(2, 1, gatedFactory(first, 2, gate, &firstCalls), nil, WithAsyncReset(), WithIdleGrace(0), WithResetCoordinator(coordinator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	second := startServer(t)
+	b, err := New(2, 1, second.factory(), nil, WithAsyncReset(), WithIdleGrace(0), WithResetCoordinator(coordinator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	pools := []*lb{a.(*lb), b.(*lb)}
+	for _, o := range pools {
+		o.Get()
+		o.Get()
+		o.allowReset()
+	}
+
+	pools[0].Get()
+	eventually(t, "the first pool to start its reset", func() bool {
+		return atomic.LoadInt32(&firstCalls) == 3
+	})
+
+	pools[1].Get()
+	pools[1].mutex.Lock()
+	deferred := pools[1].resetCall == nil
+	pools[1].unlock()
+	if !deferred {
+		t.Fatal("the second pool started a reset while the first one was resetting")
+	}
+
+	close(gate)
+	eventually(t, "the first reset to complete", func() bool {
+		pools[0].mutex.Lock()
+		defer pools[0].unlock()
+		return pools[0].resetCall == nil
+	})
+
+	pools[1].allowReset()
+	pools[1].Get()
+	eventually(t, "the second pool to reset", func() bool {
+		return second.dialCount() == 4
+	})
+}
@@ -0,0 +1,89 @@
+package grpclb
+
+import "google.golang.org/grpc"
+
+/*
+GetWithCost selects a connection for a request of the given cost, such as its
+expected payload size or duration, so that heavy and light requests are spread
+fairly. Every connection accumulates the cost of the requests it was selected
+for, and the servable connection with the lowest accumulated cost is selected,
+in round-robin order among ties, in the spirit of deficit round-robin. Only
+requests selected through GetWithCost are accounted for. Recovery works the
+same way as in Get.
+*/
+func (o *lb) GetWithCost(cost uint32) *grpc.ClientConn {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s, err := o.nextWith(nil, o.pickCheapest)
+	if err != nil || s == nil {
+		return nil
+	}
+
+	s.cost += uint64(cost)
+	return s.conn
+}
+
+/*
+pickCheapest picks the accepted slot with the lowest accumulated cost,
+preferring servable slots. The caller must hold the mutex.
+*/
+func (o *lb) pickCheapest(accept func(s *slot) bool) *slot {
+	cost := func(s *slot) float64 {
+		return float64(s.cost)
+	}
+
+	if s := o.pickMin(both(accept, o.servable), cost); s != nil {
+		return s
+	}
+
+	return o.pickMin(accept, cost)
+}
+
+/*
+pickMin picks the accepted slot with the lowest key, choosing among slots with
+equal keys as set by WithTieBreaker. It returns nil if no slot is accepted.
+The caller must hold the mutex.
+*/
+func (o *lb) pickMin(accept func(s *slot) bool, key func(s *slot) float64) *slot {
+	var min float64
+	found := false
+	for _, s := range o.slots {
+		if accept != nil && !accept(s) {
+			continue
+		}
+
+		if k := key(s); !found || k < min {
+			min, found = k, true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	return o.breakTie(both(accept, func(s *slot) bool {
+		return key(s) == min
+	}))
+}
+
+/*
+minCost returns the lowest accumulated cost among the slots, the starting cost
+of a new connection so that it does not attract all cost-accounted requests
+until it caught up. The caller must hold the mutex.
+*/
+func (o *lb) minCost() uint64 {
+	var min uint64
+	found := false
+	for _, s := range o.slots {
+		if s == nil {
+			continue
+		}
+
+		if !found || s.cost < min {
+			min, found = s.cost, true
+		}
+	}
+
+	return min
+}
@@ -0,0 +1,28 @@
+package grpclb
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+GetWithDeadline selects a connection the same way Get does and returns it with
+a context that expires after d, so that the call made on it cannot go without
+a deadline. The cancel function must be called once the call completed, even if
+the connection is nil because none could be selected.
+*/
+func (o *lb) GetWithDeadline(d time.Duration) (*grpc.ClientConn, context.Context, context.CancelFunc) {
+	return o.GetContextWithDeadline(context.Background(), d)
+}
+
+/*
+GetContextWithDeadline is GetWithDeadline deriving the context from parent.
+The returned context expires after d or with parent, whichever comes first, so
+a shorter deadline of parent is respected.
+*/
+func (o *lb) GetContextWithDeadline(parent context.Context, d time.Duration) (*grpc.ClientConn, context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, d)
+	return o.Get(), ctx, cancel
+}
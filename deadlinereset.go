@@ -0,0 +1,103 @@
+package grpclb
+
+import (
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+ErrResetDeadline is returned by a reset that was aborted because it did not
+complete within the deadline set with WithResetDeadline.
+*/
+var ErrResetDeadline = errors.New("reset exceeded its deadline")
+
+/*
+WithResetDeadline bounds the time a synchronous reset may hold the pool's
+mutex. Instead of replacing the connections one by one, the reset dials all
+the replacements first and only swaps them in once every one of them is ready
+to be installed. If that has not happened within d, the reset is aborted with
+ErrResetDeadline: the replacements are closed, including the ones the factory
+returns after the deadline, and the pool keeps serving its previous
+connections. Resets with a deadline are not batched with WithResetBatch.
+*/
+func WithResetDeadline(d time.Duration) Option {
+	return func(o *lb) {
+		o.resetDeadline = d
+	}
+}
+
+/*
+resetWithin dials replacements for all the connections and installs them if
+that completes before deadline. The caller must hold the mutex.
+*/
+func (o *lb) resetWithin(deadline time.Time) error {
+	conns := make([]*grpc.ClientConn, o.size)
+	for i := range conns {
+		conn, err := o.dialBy(deadline, o.redialer(uint32(i)))
+		if err != nil {
+			o.closeAll(conns)
+			return err
+		}
+
+		conns[i] = conn
+	}
+
+	o.replaceAll(conns)
+	return nil
+}
+
+/*
+dialBy dials with redial and gives up with ErrResetDeadline once deadline
+passes. A connection the factory returns after that is closed.
+*/
+func (o *lb) dialBy(deadline time.Time, redial func() (*grpc.ClientConn, error)) (*grpc.ClientConn, error) {
+	type result struct {
+		conn *grpc.ClientConn
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		conn, err := redial()
+		done <- result{conn, err}
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-timer.C:
+		go func() {
+			if r := <-done; r.conn != nil {
+				o.closeConn(r.conn)
+			}
+		}()
+
+		return nil, ErrResetDeadline
+	}
+}
+
+/*
+replaceAll installs conns as the connections of the pool, one per slot, and
+closes the connections they replace. The caller must hold the mutex.
+*/
+func (o *lb) replaceAll(conns []*grpc.ClientConn) {
+	indices := make([]int, len(conns))
+	for i, s := range o.slots {
+		o.detach(s)
+		if err := o.discard(s.conn); err != nil {
+			o.log("Failed to close connection: " + err.Error())
+		}
+
+		o.attach(s, conns[i])
+		s.inFlight = 0
+		indices[i] = i
+	}
+
+	o.notifyReset(indices)
+	o.notifyReleased()
+}
@@ -0,0 +1,69 @@
+package grpclb
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestResetDeadlineKeepsConns(t *testing.T) {
+	ts := startServer(t)
+	gate := make(chan struct{})
+	var calls int32
+	dial := gatedFactory(ts, 3, gate, &calls)
+
+	var mu sync.Mutex
+	var dialed []*grpc.ClientConn
+	factory := func() (*grpc.ClientConn, error) {
+		conn, err := dial()
+		mu.Lock()
+		defer mu.Unlock()
+		dialed = append(dialed, conn)
+		return conn, err
+	}
+
+	l, err := New(2, 1, factory, nil, WithResetDeadline(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+	old := []*grpc.ClientConn{o.slots[0].conn, o.slots[1].conn}
+
+	start := time.Now()
+	if err := o.ForceReset(); !errors.Is(err, ErrResetDeadline) {
+		t.Fatalf("ForceReset: got error %v, want ErrResetDeadline", err)
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("the aborted reset took %v", took)
+	}
+
+	for i, conn := range old {
+		if o.slots[i].conn != conn {
+			t.Errorf("connection %d was replaced by the aborted reset", i)
+		}
+
+		if conn.GetState() == connectivity.Shutdown {
+			t.Errorf("connection %d was closed by the aborted reset", i)
+		}
+	}
+
+	close(gate)
+	eventually(t, "the late replacement to be dialed", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(dialed) == 4
+	})
+
+	mu.Lock()
+	replacements := dialed[2:]
+	mu.Unlock()
+	for _, conn := range replacements {
+		waitState(t, conn, connectivity.Shutdown)
+	}
+}
@@ -0,0 +1,73 @@
+package grpclb
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+WithStaggeredDial spreads the initial dials made by New randomly across the
+given window instead of dialing every connection back to back, smoothing the
+burst of handshakes the backend sees when a client starts. New returns once
+every connection has been dialed, so it takes up to spread longer.
+*/
+func WithStaggeredDial(spread time.Duration) Option {
+	return func(o *lb) {
+		o.dialSpread = spread
+	}
+}
+
+/*
+dialAll creates the initial size connections. With WithStaggeredDial every
+dial starts after a random delay within the spread window. If any dial fails,
+the connections created so far are closed and the first error is returned.
+*/
+func (o *lb) dialAll(size uint32) ([]*grpc.ClientConn, error) {
+	conns := make([]*grpc.ClientConn, size)
+	errs := make([]error, size)
+
+	if o.dialSpread <= 0 {
+		for i := range conns {
+			if conns[i], errs[i] = o.dial(uint32(i)); errs[i] != nil {
+				break
+			}
+		}
+	} else {
+		var wg sync.WaitGroup
+		for i := range conns {
+			delay := time.Duration(o.rand.Int63n(int64(o.dialSpread)))
+
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				time.Sleep(delay)
+				conns[i], errs[i] = o.dial(uint32(i))
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			o.closeAll(conns)
+			return nil, err
+		}
+	}
+
+	return conns, nil
+}
+
+/*
+closeAll closes every non-nil connection, ignoring errors. It is used to clean
+up after a failed construction.
+*/
+func (o *lb) closeAll(conns []*grpc.ClientConn) {
+	for _, conn := range conns {
+		if conn != nil {
+			o.closeConn(conn)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package grpclb
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+DialStats summarizes how long the factory took to create connections since
+the load balancer was created, covering the initial dials, resets, recoveries
+and replacements. Last is the duration of the most recent call. The durations
+encode to JSON as duration strings.
+*/
+type DialStats struct {
+	Count uint64        `json:"count"`
+	Last  time.Duration `json:"last"`
+	Min   time.Duration `json:"min"`
+	Max   time.Duration `json:"max"`
+	Mean  time.Duration `json:"mean"`
+}
+
+/*
+MarshalJSON implements json.Marshaler.
+*/
+func (d DialStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count uint64   `json:"count"`
+		Last  Duration `json:"last"`
+		Min   Duration `json:"min"`
+		Max   Duration `json:"max"`
+		Mean  Duration `json:"mean"`
+	}{d.Count, Duration(d.Last), Duration(d.Min), Duration(d.Max), Duration(d.Mean)})
+}
+
+/*
+WithOnSlowDial calls cb with the index of the connection and the time the
+factory took whenever creating a connection takes longer than threshold, to
+tell slow name resolution or handshakes apart from other reset slowness.
+Overflow connections are reported with indices from the size of the pool on.
+cb runs in its own goroutine.
+*/
+func WithOnSlowDial(threshold time.Duration, cb func(index int, took time.Duration)) Option {
+	return func(o *lb) {
+		o.slowDial = threshold
+		o.onSlowDial = cb
+	}
+}
+
+/*
+timed wraps factory so that every call is recorded in the dial stats as a dial
+for the connection at index.
+*/
+func (o *lb) timed(index uint32, factory func() (*grpc.ClientConn, error)) func() (*grpc.ClientConn, error) {
+	return func() (*grpc.ClientConn, error) {
+		start := time.Now()
+		conn, err := factory()
+		o.recordDial(index, time.Since(start))
+		return conn, err
+	}
+}
+
+/*
+recordDial adds a factory call that took took to the dial stats and reports it
+if it was slow. It takes the dial stats mutex, so it may be called with or
+without the pool's mutex held.
+*/
+func (o *lb) recordDial(index uint32, took time.Duration) {
+	o.dialMutex.Lock()
+	d := &o.dialStats
+	if d.Count == 0 || took < d.Min {
+		d.Min = took
+	}
+
+	if took > d.Max {
+		d.Max = took
+	}
+
+	d.Count++
+	d.Last = took
+	o.dialTotal += took
+	d.Mean = o.dialTotal / time.Duration(d.Count)
+	o.dialMutex.Unlock()
+
+	if o.onSlowDial != nil && took > o.slowDial {
+		go o.onSlowDial(int(index), took)
+	}
+}
+
+/*
+dials returns a copy of the dial stats.
+*/
+func (o *lb) dials() DialStats {
+	o.dialMutex.Lock()
+	defer o.dialMutex.Unlock()
+
+	return o.dialStats
+}
@@ -0,0 +1,100 @@
+package grpclb
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+acquireOn acquires connections from the pool until it is handed conn, and
+returns that acquisition.
+*/
+func acquireOn(t testing.TB, o *lb, conn *grpc.ClientConn) *PooledConn {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		pc, err := o.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pc.Conn() == conn {
+			return pc
+		}
+		pc.Done()
+	}
+
+	t.Fatal("the connection was never acquired")
+	return nil
+}
+
+func TestShrinkDrainsConnWithRequestsInFlight(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 2)
+	connectAll(t, o)
+
+	removed := o.slots[1].conn
+	pc := acquireOn(t, o, removed)
+
+	if err := o.Resize(1); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if state := removed.GetState(); state == connectivity.Shutdown {
+		t.Fatal("the removed connection was closed with a request in flight")
+	}
+
+	pc.Done()
+	waitState(t, removed, connectivity.Shutdown)
+}
+
+func TestShrinkDrainTimeoutClosesConn(t *testing.T) {
+	ts := startServer(t)
+	var mu sync.Mutex
+	var logged []string
+	logger := func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, msg)
+	}
+
+	l, err := New(2, 1, ts.factory(), logger, WithDrainTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+	connectAll(t, o)
+
+	removed := o.slots[1].conn
+	pc := acquireOn(t, o, removed)
+	defer pc.Done()
+
+	start := time.Now()
+	if err := o.Resize(1); err != nil {
+		t.Fatal(err)
+	}
+
+	waitState(t, removed, connectivity.Shutdown)
+	if took := time.Since(start); took < 100*time.Millisecond {
+		t.Errorf("the removed connection was closed after %v, before the drain timeout", took)
+	}
+
+	eventually(t, "the timed out drain to be logged", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, msg := range logged {
+			if strings.HasPrefix(msg, "Drain timed out") && strings.HasSuffix(msg, " 1") {
+				return true
+			}
+		}
+		return false
+	})
+}
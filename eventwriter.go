@@ -0,0 +1,131 @@
+package grpclb
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+WithJSONEventWriter writes every reset attempt, including per-slot recoveries
+and RecycleConn, and every connectivity state change of the connections to w,
+one JSON object per line, for structured observability without a logging
+framework. Each object carries the time, the pool's name set with WithName
+and the kind of event, "reset" or "state"; resets add the fields of
+ResetEvent, state changes the index and the state's name. The writes happen
+without the pool's mutex held and one at a time, so lines never interleave.
+Write errors are ignored.
+*/
+func WithJSONEventWriter(w io.Writer) Option {
+	return func(o *lb) {
+		o.events = &eventWriter{w: w}
+	}
+}
+
+/*
+eventWriter serializes the writes of JSON lines to w.
+*/
+type eventWriter struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+/*
+write encodes v and writes it to w as a single line.
+*/
+func (e *eventWriter) write(v interface{}) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	_, _ = e.w.Write(append(line, '\n'))
+}
+
+/*
+resetLine is the JSON form of a ResetEvent.
+*/
+type resetLine struct {
+	Time     time.Time    `json:"time"`
+	Pool     string       `json:"pool,omitempty"`
+	Event    string       `json:"event"`
+	Trigger  ResetTrigger `json:"trigger"`
+	Indices  []int        `json:"indices"`
+	Error    string       `json:"error,omitempty"`
+	Duration Duration     `json:"duration"`
+}
+
+/*
+stateLine is the JSON form of a state change.
+*/
+type stateLine struct {
+	Time  time.Time `json:"time"`
+	Pool  string    `json:"pool,omitempty"`
+	Event string    `json:"event"`
+	Index uint32    `json:"index"`
+	State string    `json:"state"`
+}
+
+/*
+emit queues the write of v to the event writer, if WithJSONEventWriter is set.
+The caller must hold the mutex.
+*/
+func (o *lb) emit(v interface{}) {
+	if o.events == nil {
+		return
+	}
+
+	events := o.events
+	o.pending = append(o.pending, func() {
+		events.write(v)
+	})
+}
+
+/*
+emitReset queues the JSON line describing event. The caller must hold the
+mutex.
+*/
+func (o *lb) emitReset(event ResetEvent) {
+	if o.events == nil {
+		return
+	}
+
+	line := resetLine{
+		Time:     event.Time,
+		Pool:     o.name,
+		Event:    "reset",
+		Trigger:  event.Trigger,
+		Indices:  event.Indices,
+		Duration: Duration(event.Duration),
+	}
+
+	if event.Err != nil {
+		line.Error = event.Err.Error()
+	}
+
+	o.emit(line)
+}
+
+/*
+emitState queues the JSON line describing the slot's connection moving to
+state. The caller must hold the mutex.
+*/
+func (o *lb) emitState(s *slot, state connectivity.State) {
+	if o.events == nil {
+		return
+	}
+
+	o.emit(stateLine{
+		Time:  time.Now(),
+		Pool:  o.name,
+		Event: "state",
+		Index: s.index,
+		State: state.String(),
+	})
+}
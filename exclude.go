@@ -0,0 +1,36 @@
+package grpclb
+
+import "google.golang.org/grpc"
+
+/*
+GetExcluding selects a connection the same way Get does, but avoids exclude,
+typically the connection a failed RPC has just been sent on, so that a retry
+goes to a different one. A servable connection other than exclude is preferred,
+then any other connection; exclude is only returned if it is the only option.
+*/
+func (o *lb) GetExcluding(exclude *grpc.ClientConn) *grpc.ClientConn {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	other := func(s *slot) bool {
+		return s.conn != exclude
+	}
+
+	s, err := o.nextWith(other, func(accept func(s *slot) bool) *slot {
+		if s := o.pick(both(accept, o.servable)); s != nil {
+			return s
+		}
+
+		return o.pick(accept)
+	})
+	if err == nil && s == nil {
+		// Only exclude is left, so it is selected like in Get.
+		s, err = o.next(nil)
+	}
+
+	if err != nil || s == nil {
+		return nil
+	}
+
+	return s.conn
+}
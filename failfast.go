@@ -0,0 +1,36 @@
+package grpclb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+WithFailFast makes New verify that the first connection becomes Ready within
+probeTimeout and fail otherwise, so that a wrong address or a backend that is
+down is caught at startup. The other connections are not waited for. Without
+the option New does not wait for any connection.
+*/
+func WithFailFast(probeTimeout time.Duration) Option {
+	return func(o *lb) {
+		o.failFast = probeTimeout
+	}
+}
+
+/*
+probeReady waits for conn to become Ready within the fail fast timeout and
+returns an error describing the state it was left in otherwise.
+*/
+func (o *lb) probeReady(conn *grpc.ClientConn) error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.failFast)
+	defer cancel()
+
+	if !waitReady(ctx, conn) {
+		return fmt.Errorf("connection to %s not ready within %s, state %s: %w", conn.Target(), o.failFast, conn.GetState(), ctx.Err())
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package grpclb
+
+import (
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+/*
+fastPath is an immutable snapshot of the pool that Get selects from without
+taking the mutex in the common case where the connection it lands on is
+usable. It is only published while the configuration is plain round-robin;
+any feature that needs to look at more than the connectivity state of a
+single connection sends every call through the locked path instead.
+*/
+type fastPath struct {
+	conns  []*grpc.ClientConn
+	uses   []*uint64
+	stride uint64
+}
+
+/*
+fastGet selects the next connection from the published snapshot, advancing an
+atomic offset. It returns nil when there is no snapshot or when the connection
+it lands on is not usable, in which case the caller falls back to the locked
+path that handles recovery.
+*/
+func (o *lb) fastGet() *grpc.ClientConn {
+	fast, _ := o.fast.Load().(*fastPath)
+	if fast == nil {
+		return nil
+	}
+
+	next := atomic.AddUint64(&o.fastNext, 1) - 1
+	i := next * fast.stride % uint64(len(fast.conns))
+	conn := fast.conns[i]
+	if !o.usable(conn.GetState()) {
+		return nil
+	}
+
+	atomic.AddUint64(&o.fastUseCount, 1)
+	atomic.AddUint64(fast.uses[i], 1)
+	return conn
+}
+
+/*
+plain reports whether selection is plain round-robin over the connectivity
+state, so that the fast path picks the same connections the locked path
+would. The caller must hold the mutex.
+*/
+func (o *lb) plain() bool {
+	return !o.closed && o.strategy == RoundRobin && !o.latencyAffinity &&
+		o.outlier == nil && o.priorities == nil && !o.readySet &&
+		o.validator == nil && o.preferRecovered <= 0 && o.paused == 0 &&
+		o.cooldown <= 0 && o.leased == 0 && !o.pinned && !o.orca &&
+		o.slowStart <= 0 && !o.goAwayRefresh && !o.windowed()
+}
+
+/*
+publish replaces the snapshot used by the fast path with the current
+connections, or withdraws it if the configuration is not plain. The caller
+must hold the mutex.
+*/
+func (o *lb) publish() {
+	o.fastStale = false
+	if !o.plain() {
+		o.fast.Store((*fastPath)(nil))
+		return
+	}
+
+	fast := &fastPath{
+		conns:  make([]*grpc.ClientConn, len(o.slots)),
+		uses:   make([]*uint64, len(o.slots)),
+		stride: uint64(o.stride),
+	}
+	for i, s := range o.slots {
+		fast.conns[i], fast.uses[i] = s.conn, &s.uses
+	}
+
+	o.fast.Store(fast)
+}
+
+/*
+invalidate withdraws the fast path snapshot right away, before a connection
+it may contain is closed, and has it republished when the mutex is released.
+The caller must hold the mutex.
+*/
+func (o *lb) invalidate() {
+	o.fast.Store((*fastPath)(nil))
+	o.fastStale = true
+}
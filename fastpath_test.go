@@ -0,0 +1,87 @@
+package grpclb
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+/*
+benchmarkPaths runs bench against a ready pool served by the lock-free fast
+path and against one that a trivial validator sends through the locked path.
+*/
+func benchmarkPaths(b *testing.B, bench func(b *testing.B, o *lb)) {
+	accept := func(*grpc.ClientConn) bool {
+		return true
+	}
+
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{
+		{"fast", nil},
+		{"locked", []Option{WithValidator(accept)}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			ts := startServer(b)
+			o := newTestLB(b, ts, 8, c.opts...)
+			connectAll(b, o)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			bench(b, o)
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	benchmarkPaths(b, func(b *testing.B, o *lb) {
+		for i := 0; i < b.N; i++ {
+			if o.Get() == nil {
+				b.Fatal("Get returned nil")
+			}
+		}
+	})
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	benchmarkPaths(b, func(b *testing.B, o *lb) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if o.Get() == nil {
+					b.Error("Get returned nil")
+					return
+				}
+			}
+		})
+	})
+}
+
+func TestFastPathMatchesLockedPath(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 3)
+	connectAll(t, o)
+
+	if fast, _ := o.fast.Load().(*fastPath); fast == nil {
+		t.Fatal("no fast path published for a plain round-robin pool")
+	}
+
+	seen := make(map[*grpc.ClientConn]int)
+	for i := 0; i < 30; i++ {
+		seen[o.Get()]++
+	}
+
+	for _, s := range o.slots {
+		if seen[s.conn] != 10 {
+			t.Errorf("connection %d selected %d times, want 10", s.index, seen[s.conn])
+		}
+	}
+
+	if err := o.Pin(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if fast, _ := o.fast.Load().(*fastPath); fast != nil {
+		t.Fatal("the fast path stayed published while a pin is set")
+	}
+}
@@ -0,0 +1,65 @@
+package grpclb
+
+import "time"
+
+/*
+resetCall is a reset in progress. Only one runs at a time: callers that find
+one in flight join it instead of closing and redialing the connections again.
+done is closed once the reset completed and err holds its result. trigger,
+start and indices describe the reset for the history.
+*/
+type resetCall struct {
+	done    chan struct{}
+	err     error
+	trigger ResetTrigger
+	start   time.Time
+	indices []int
+}
+
+/*
+beginReset records that a reset of the whole pool is in progress. The caller
+must hold the mutex and make sure no other reset is in flight.
+*/
+func (o *lb) beginReset(trigger ResetTrigger) *resetCall {
+	call := &resetCall{
+		done:    make(chan struct{}),
+		trigger: trigger,
+		start:   time.Now(),
+		indices: allIndices(o.size),
+	}
+	o.resetCall = call
+	return call
+}
+
+/*
+endReset completes call with err, records it in the reset history and wakes up
+the callers waiting for it. An automatic reset also ends at the reset
+coordinator. The caller must hold the mutex.
+*/
+func (o *lb) endReset(call *resetCall, err error) {
+	call.err = err
+	o.resetCall = nil
+	if call.trigger == ResetAuto && o.coordinator != nil {
+		o.coordinator.end()
+	}
+	o.classify(err)
+	o.recordReset(call.trigger, call.start, call.indices, err)
+	close(call.done)
+}
+
+/*
+joinReset waits for call to complete and returns its result. The mutex is
+released while waiting, so the reset can make progress. The caller must hold
+the mutex.
+*/
+func (o *lb) joinReset(call *resetCall) error {
+	o.mutex.Unlock()
+	<-call.done
+	o.mutex.Lock()
+
+	if o.closed {
+		return ErrClosed
+	}
+
+	return call.err
+}
@@ -0,0 +1,59 @@
+package grpclb
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForceResetJoinsResetInFlight(t *testing.T) {
+	ts := startServer(t)
+	gate := make(chan struct{})
+	var calls int32
+
+	l, err := New(2, 1, gatedFactory(ts, 2, gate, &calls), nil, WithAsyncReset(), WithIdleGrace(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+
+	o.Get()
+	o.Get()
+	o.allowReset()
+	o.Get()
+	eventually(t, "the background reset to dial", func() bool {
+		return atomic.LoadInt32(&calls) == 3
+	})
+
+	var wg sync.WaitGroup
+	var returned int32
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = o.ForceReset()
+			atomic.AddInt32(&returned, 1)
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&returned); n != 0 {
+		t.Fatalf("%d calls to ForceReset returned before the reset in flight completed", n)
+	}
+
+	close(gate)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("ForceReset %d: %v", i, err)
+		}
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Errorf("factory called %d times, want 4 for a single coalesced reset", n)
+	}
+}
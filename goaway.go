@@ -0,0 +1,116 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// goAwayActivity is how recently a connection must have been selected for its
+// drop from Ready to Idle to count as a GOAWAY rather than the channel's idle
+// timeout, which needs a period without RPCs on the connection.
+const goAwayActivity = 10 * time.Second
+
+/*
+WithGoAwayRefresh replaces a connection as soon as it drops from Ready to Idle,
+which is how gRPC surfaces a GOAWAY sent by a backend shutting down gracefully,
+instead of waiting for the next request to reconnect it. The replacement is
+dialed in the background, so it resolves the target afresh, and installed in
+the same slot, which keeps its place in the pool, its lease and pause state.
+The old connection is drained like a connection removed by Resize: it is
+closed once its requests acquired through Acquire have been released. A
+channel also drops to Idle after its idle timeout, which only happens after a
+period without RPCs; to leave those connections alone, a connection is only
+replaced if it has requests in flight or was selected within the last ten
+seconds. Selection takes the pool's mutex with this option.
+*/
+func WithGoAwayRefresh() Option {
+	return func(o *lb) {
+		o.goAwayRefresh = true
+	}
+}
+
+/*
+goneAway notes the slot's latest state and replaces its connection if it just
+went from Ready to Idle while in use. The caller must hold the mutex.
+*/
+func (o *lb) goneAway(s *slot, state connectivity.State) {
+	previous := s.lastState
+	s.lastState = state
+	if previous != connectivity.Ready || state != connectivity.Idle || s.recovering || o.closed {
+		return
+	}
+
+	if s.inFlight == 0 && time.Since(s.pickedAt) >= goAwayActivity {
+		return
+	}
+
+	o.log("Connection " + itoa(s.index) + " went idle after being ready, replacing it")
+	s.recovering = true
+	go o.replaceGoneAway(s, s.conn, o.redialer(s.index))
+}
+
+/*
+replaceGoneAway dials a replacement for old with redial without holding the
+mutex and installs it in the slot, unless the slot's connection changed or the
+slot left the pool in the meantime. The requests still in flight on old move
+with it to a retired slot that drains it.
+*/
+func (o *lb) replaceGoneAway(s *slot, old *grpc.ClientConn, redial func() (*grpc.ClientConn, error)) {
+	conn, err := redial()
+
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s.recovering = false
+	o.classify(err)
+	if err != nil {
+		o.log("Failed to replace connection " + itoa(s.index) + ": " + err.Error())
+		return
+	}
+
+	if o.closed || s.conn != old || o.bySlot[old] != s {
+		o.closeConn(conn)
+		return
+	}
+
+	retired := &slot{index: s.index, conn: old, inFlight: s.inFlight, bytesInFlight: s.bytesInFlight}
+	o.detach(s)
+	if o.store != nil {
+		o.store.invalidate(old)
+	}
+
+	o.attach(s, conn)
+	s.inFlight = 0
+	o.notifyReset([]int{int(s.index)})
+	o.notifyReleased()
+
+	if o.retiring == nil {
+		o.retiring = make(map[*grpc.ClientConn]*slot)
+	}
+	o.retiring[old] = retired
+
+	go func() {
+		o.drain([]*slot{retired})
+
+		o.mutex.Lock()
+		delete(o.retiring, old)
+		o.unlock()
+	}()
+}
+
+/*
+holder returns the slot whose in-flight counters cover a request acquired from
+s while it held conn: s itself, or the retired slot draining conn after
+WithGoAwayRefresh replaced it. The caller must hold the mutex.
+*/
+func (o *lb) holder(s *slot, conn *grpc.ClientConn) *slot {
+	if s.conn != conn {
+		if retired, ok := o.retiring[conn]; ok {
+			return retired
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,91 @@
+package grpclb
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestGoAwayRefreshOnServerShutdown(t *testing.T) {
+	old, replacement := startServer(t), startServer(t)
+	var moved int32
+	factory := func() (*grpc.ClientConn, error) {
+		if atomic.LoadInt32(&moved) == 1 {
+			return replacement.factory()()
+		}
+
+		return old.factory()()
+	}
+
+	l, err := New(1, 1, factory, nil, WithGoAwayRefresh())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+	connectAll(t, o)
+
+	pc, err := o.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o.mutex.Lock()
+	s := o.slots[0]
+	o.unlock()
+
+	atomic.StoreInt32(&moved, 1)
+	old.server.GracefulStop()
+
+	eventually(t, "the connection to be replaced", func() bool {
+		o.mutex.Lock()
+		defer o.unlock()
+		return s.conn != pc.Conn()
+	})
+
+	o.mutex.Lock()
+	if o.slots[0] != s {
+		t.Error("the refresh replaced the slot instead of its connection")
+	}
+	o.unlock()
+
+	if n := replacement.dialCount(); n != 1 {
+		t.Errorf("dialed the replacement server %d times, want 1", n)
+	}
+
+	if pc.Conn().GetState() == connectivity.Shutdown {
+		t.Fatal("the old connection was closed with a request in flight")
+	}
+
+	pc.Done()
+	waitState(t, pc.Conn(), connectivity.Shutdown)
+}
+
+func TestGoAwayRefreshIgnoresIdleTimeout(t *testing.T) {
+	ts := startServer(t)
+	l, err := New(1, 1, ts.factory(grpc.WithIdleTimeout(100*time.Millisecond)), nil, WithGoAwayRefresh())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+
+	o.mutex.Lock()
+	conn := o.slots[0].conn
+	o.unlock()
+
+	conn.Connect()
+	waitState(t, conn, connectivity.Ready)
+	waitState(t, conn, connectivity.Idle)
+	time.Sleep(100 * time.Millisecond)
+
+	o.mutex.Lock()
+	defer o.unlock()
+	if o.slots[0].conn != conn || ts.dialCount() != 1 {
+		t.Error("a connection that went idle on its idle timeout was replaced")
+	}
+}
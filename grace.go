@@ -0,0 +1,41 @@
+package grpclb
+
+import "time"
+
+/*
+WithRecoveryGrace gives a connection that stopped being servable d to recover
+on its own through gRPC's reconnection before the pool resets it, even if the
+minimum retry interval would allow an immediate reset. Brief blips then no
+longer cause close-and-redial churn. The grace period starts when selection
+first notices the connection is not servable. With a ready set it applies to
+the set becoming empty, with WithPerSlotRecovery to every slot's redial.
+*/
+func WithRecoveryGrace(d time.Duration) Option {
+	return func(o *lb) {
+		o.recoveryGrace = d
+	}
+}
+
+/*
+pastGrace reports whether recovery should start for a condition that is bad,
+tracking when it was first observed in since. A good condition clears since.
+Without a recovery grace period, a bad condition needs recovery right away.
+The caller must hold the mutex.
+*/
+func (o *lb) pastGrace(since *time.Time, bad bool) bool {
+	if !bad {
+		*since = time.Time{}
+		return false
+	}
+
+	if o.recoveryGrace <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if since.IsZero() {
+		*since = now
+	}
+
+	return now.Sub(*since) >= o.recoveryGrace
+}
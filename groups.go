@@ -0,0 +1,41 @@
+package grpclb
+
+/*
+WithGroups assigns a group label to every connection, one entry per index, for
+example the tenant or logical backend the connection serves. Groups do not
+affect selection; GroupStats aggregates the connection statistics per group.
+Connections added by Resize join the group of the last connection. New returns
+an error if the number of groups does not match the size.
+*/
+func WithGroups(groups []string) Option {
+	return func(o *lb) {
+		o.groups = append([]string(nil), groups...)
+	}
+}
+
+/*
+GroupStats returns a snapshot of the pool per group assigned with WithGroups.
+Each entry only covers the connections of its group: the counters are summed
+over them, so Failures against Successes gives the group's error rate, and
+Conns lists them, while the reset times are those of the whole pool. Overflow
+connections belong to no group. The map is empty without groups.
+*/
+func (o *lb) GroupStats() map[string]PoolStats {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	groups := make(map[string][]*slot)
+	if o.groups != nil {
+		for _, s := range o.slots {
+			group := o.groups[s.index]
+			groups[group] = append(groups[group], s)
+		}
+	}
+
+	stats := make(map[string]PoolStats, len(groups))
+	for group, slots := range groups {
+		stats[group] = o.stats(slots)
+	}
+
+	return stats
+}
@@ -0,0 +1,18 @@
+package grpclbhttp
+
+import (
+	"expvar"
+
+	grpclb "github.com/golanguzb70/grpc-lb"
+)
+
+/*
+PublishStats publishes the stats of lb as the expvar variable name, so a fresh
+snapshot is served under /debug/vars on every request. Like expvar.Publish, it
+panics if name is already in use.
+*/
+func PublishStats(name string, lb grpclb.ReadOnlyLB) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return lb.Stats()
+	}))
+}
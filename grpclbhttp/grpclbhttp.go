@@ -0,0 +1,37 @@
+/*
+Package grpclbhttp exposes grpclb load balancers over HTTP, as readiness
+probes or as expvar variables. It is kept apart from grpclb so that the core
+package does not depend on net/http.
+*/
+package grpclbhttp
+
+import (
+	"net/http"
+
+	grpclb "github.com/golanguzb70/grpc-lb"
+)
+
+/*
+HealthHandler returns an http.Handler reporting whether lb can serve requests,
+suitable as a Kubernetes readiness probe. It responds 200 while at least one
+connection is Ready and 503 otherwise, including once the load balancer is
+closed or draining in Shutdown. The body is a short plain text reason.
+*/
+func HealthHandler(lb grpclb.ReadOnlyLB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := lb.Stats()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		switch {
+		case stats.Closed:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("draining\n"))
+		case stats.Ready == 0:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("no ready connections\n"))
+		default:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("ok\n"))
+		}
+	})
+}
@@ -0,0 +1,30 @@
+/*
+Package grpclbtest provides helpers for testing code built on grpclb load
+balancers, such as custom select middlewares or weighted strategies.
+*/
+package grpclbtest
+
+import grpclb "github.com/golanguzb70/grpc-lb"
+
+/*
+Distribution performs n selections on lb and returns how many of them landed
+on each slot index, as reported by GetIndexed. Selections that returned no
+connection are counted under -1. A test can compare the counts against the
+share it expects each connection to receive:
+
+	counts := grpclbtest.Distribution(lb, 1000)
+	for i := 0; i < size; i++ {
+		if counts[i] < 1000/size-50 {
+			t.Errorf("connection %d selected %d times", i, counts[i])
+		}
+	}
+*/
+func Distribution(lb grpclb.LB, n int) map[int]int {
+	counts := make(map[int]int)
+	for i := 0; i < n; i++ {
+		_, index := lb.GetIndexed()
+		counts[index]++
+	}
+
+	return counts
+}
@@ -0,0 +1,54 @@
+package grpclb
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+/*
+Handle identifies the connection a selection returned: the connection, the
+index of its slot, the target it was dialed to and its tags set with WithTags.
+Pass it to FailedHandle when a request made on it fails.
+*/
+type Handle struct {
+	Conn   *grpc.ClientConn
+	Index  uint32
+	Target string
+	Tags   map[string]string
+}
+
+/*
+GetHandle selects a connection the same way Get does and returns a handle to
+it. An error is returned if no connection could be selected.
+*/
+func (o *lb) GetHandle() (Handle, error) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s, err := o.next(nil)
+	if err != nil {
+		return Handle{}, err
+	}
+
+	if s == nil {
+		return Handle{}, errNoConn
+	}
+
+	return Handle{Conn: s.conn, Index: s.index, Target: s.conn.Target(), Tags: o.tagsOf(s.index)}, nil
+}
+
+/*
+FailedHandle records err as the outcome of a request made on the handle's
+connection, exactly like Report, and returns err annotated with the index and
+target of the connection for logging. The annotated error wraps err; a nil err
+records a success and returns nil.
+*/
+func (o *lb) FailedHandle(h Handle, err error) error {
+	o.Report(h.Conn, err)
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("connection %d (%s): %w", h.Index, h.Target, err)
+}
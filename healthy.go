@@ -0,0 +1,46 @@
+package grpclb
+
+import (
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// ErrNoHealthy is returned by GetHealthy when no connection is Ready.
+var ErrNoHealthy = errors.New("no healthy connection available")
+
+/*
+GetHealthy is a strict variant of Get that only returns a Ready connection,
+which also passes the validator set by WithValidator, instead of handing out a
+degraded one. If there is none, recovery is triggered exactly like in Get and
+ErrNoHealthy is returned, so the caller can fail fast or fall back explicitly.
+A failed reset returns its error instead.
+*/
+func (o *lb) GetHealthy() (*grpc.ClientConn, error) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s, err := o.next(o.healthy)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil {
+		return nil, ErrNoHealthy
+	}
+
+	return s.conn, nil
+}
+
+/*
+healthy reports whether the slot's connection is Ready and valid. The caller
+must hold the mutex.
+*/
+func (o *lb) healthy(s *slot) bool {
+	if s.conn.GetState() != connectivity.Ready {
+		return false
+	}
+
+	return o.validator == nil || o.validator(s.conn)
+}
@@ -0,0 +1,85 @@
+package grpclb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// errNoConn is returned by helpers that run calls on the pool when there is
+// no connection to run them on.
+var errNoConn = errors.New("no connection available")
+
+/*
+hedgeResult is the outcome of one attempt of a hedged call on conn.
+*/
+type hedgeResult struct {
+	conn  *grpc.ClientConn
+	value interface{}
+	err   error
+}
+
+/*
+Hedge runs fn on a connection and, if it has not returned after delay, runs it
+again in parallel on a different connection, see GetExcluding. The outcome of
+the attempt that returns first is returned and the context passed to the other
+one is cancelled. Outcomes of completed attempts are passed to Report; the
+cancelled attempt is not reported. fn must be safe to run twice, which makes
+hedging suitable for idempotent calls such as reads.
+*/
+func (o *lb) Hedge(ctx context.Context, delay time.Duration, fn func(ctx context.Context, conn *grpc.ClientConn) (interface{}, error)) (interface{}, error) {
+	first := o.Get()
+	if first == nil {
+		return nil, errNoConn
+	}
+
+	r := o.hedge(ctx, delay, first, fn, o.Report)
+	return r.value, r.err
+}
+
+/*
+hedge is Hedge starting on first and passing the outcomes of completed
+attempts to report. The result carries the connection of the attempt that
+returned first, or first if ctx ended the call.
+*/
+func (o *lb) hedge(ctx context.Context, delay time.Duration, first *grpc.ClientConn, fn func(ctx context.Context, conn *grpc.ClientConn) (interface{}, error), report func(conn *grpc.ClientConn, err error)) hedgeResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	run := func(conn *grpc.ClientConn) {
+		go func() {
+			value, err := fn(ctx, conn)
+			if err == nil || ctx.Err() == nil {
+				report(conn, err)
+			}
+
+			results <- hedgeResult{conn: conn, value: value, err: err}
+		}()
+	}
+
+	run(first)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case r := <-results:
+		return r
+	case <-timer.C:
+		if second := o.GetExcluding(first); second != nil && second != first {
+			run(second)
+		}
+	case <-ctx.Done():
+		return hedgeResult{conn: first, err: ctx.Err()}
+	}
+
+	select {
+	case r := <-results:
+		return r
+	case <-ctx.Done():
+		return hedgeResult{conn: first, err: ctx.Err()}
+	}
+}
@@ -0,0 +1,155 @@
+package grpclb
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+/*
+testServer is a gRPC server with the health service, listening in memory.
+*/
+type testServer struct {
+	listener *bufconn.Listener
+	server   *grpc.Server
+	dials    int32
+}
+
+/*
+startServer starts a test server that is stopped when the test ends.
+*/
+func startServer(t testing.TB) *testServer {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+	return &testServer{listener: listener, server: server}
+}
+
+/*
+factory returns a factory creating lazily connecting connections to the
+server, counting every call in dials.
+*/
+func (ts *testServer) factory(opts ...grpc.DialOption) func() (*grpc.ClientConn, error) {
+	return func() (*grpc.ClientConn, error) {
+		atomic.AddInt32(&ts.dials, 1)
+		return grpc.NewClient("passthrough:///bufconn", append([]grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return ts.listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}, opts...)...)
+	}
+}
+
+/*
+dialCount returns how often the factory has been called.
+*/
+func (ts *testServer) dialCount() int32 {
+	return atomic.LoadInt32(&ts.dials)
+}
+
+/*
+gatedFactory returns a factory that dials the server for the first n calls
+and blocks every later call until gate is closed, counting all calls.
+*/
+func gatedFactory(ts *testServer, n int32, gate <-chan struct{}, calls *int32) func() (*grpc.ClientConn, error) {
+	dial := ts.factory()
+	return func() (*grpc.ClientConn, error) {
+		if atomic.AddInt32(calls, 1) > n {
+			<-gate
+		}
+
+		return dial()
+	}
+}
+
+/*
+newTestLB creates a load balancer of the given size on the server's factory
+that is closed when the test ends.
+*/
+func newTestLB(t testing.TB, ts *testServer, size uint32, opts ...Option) *lb {
+	t.Helper()
+
+	l, err := New(size, 1, ts.factory(), nil, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	return l.(*lb)
+}
+
+/*
+allowReset lifts the minimum retry interval for the next automatic reset.
+*/
+func (o *lb) allowReset() {
+	o.mutex.Lock()
+	o.lastReset = time.Time{}
+	o.unlock()
+}
+
+/*
+eventually fails the test if cond does not hold within five seconds.
+*/
+func eventually(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+/*
+waitState waits until conn reaches state.
+*/
+func waitState(t testing.TB, conn *grpc.ClientConn, state connectivity.State) {
+	t.Helper()
+
+	eventually(t, "state "+state.String(), func() bool {
+		return conn.GetState() == state
+	})
+}
+
+/*
+connectAll connects every connection of the pool and waits until they are
+ready.
+*/
+func connectAll(t testing.TB, o *lb) {
+	t.Helper()
+
+	o.mutex.Lock()
+	conns := make([]*grpc.ClientConn, 0, len(o.slots))
+	for _, s := range o.slots {
+		conns = append(conns, s.conn)
+	}
+	o.unlock()
+
+	for _, conn := range conns {
+		conn.Connect()
+		waitState(t, conn, connectivity.Ready)
+	}
+}
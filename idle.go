@@ -0,0 +1,62 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+DefaultIdleGrace is how long a connection found Idle is given to connect after
+being nudged, unless WithIdleGrace changes it.
+*/
+const DefaultIdleGrace = time.Second
+
+/*
+WithIdleGrace sets how long selection waits for a connection it finds Idle
+before treating it as unhealthy. Idle is the normal state of a connection that
+has not been used yet or went idle after a period without RPCs, so instead of
+resetting it the pool calls Connect on it and leaves it alone while it is
+Idle or Connecting for the grace period d. A connection that reaches
+TransientFailure is handled as usual right away. The default is
+DefaultIdleGrace; a grace of 0 or less treats Idle like any other state that
+is not usable.
+*/
+func WithIdleGrace(d time.Duration) Option {
+	return func(o *lb) {
+		o.idleGrace = d
+	}
+}
+
+/*
+nudged reports whether the slot's connection is idle or connecting after
+selection nudged it out of Idle, within the idle grace period, and nudges a
+connection found Idle for the first time. A connection that is Idle again once
+the grace period has passed connected in the meantime and went idle after a
+period without RPCs, possibly served only by the lock-free fast path, so it is
+nudged anew. The selection path clears the nudge once it finds the connection
+servable. The caller must hold the mutex.
+*/
+func (o *lb) nudged(s *slot) bool {
+	if o.idleGrace <= 0 {
+		return false
+	}
+
+	switch s.conn.GetState() {
+	case connectivity.Idle:
+		if s.nudgedAt.IsZero() || time.Since(s.nudgedAt) >= o.idleGrace {
+			s.nudgedAt = time.Now()
+			s.conn.Connect()
+			return true
+		}
+	case connectivity.Connecting:
+		if s.nudgedAt.IsZero() {
+			return false
+		}
+	default:
+		s.nudgedAt = time.Time{}
+		return false
+	}
+
+	return time.Since(s.nudgedAt) < o.idleGrace
+}
@@ -0,0 +1,71 @@
+package grpclb
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestIdleConnIsConnectedInsteadOfReset(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 1, WithIdleGrace(time.Second))
+
+	conn := o.Get()
+	if conn == nil {
+		t.Fatal("Get returned nil")
+	}
+
+	o.allowReset()
+	if got := o.Get(); got != conn {
+		t.Fatal("Get replaced the connection while it was connecting")
+	}
+
+	waitState(t, conn, connectivity.Ready)
+	if n := ts.dialCount(); n != 1 {
+		t.Fatalf("factory called %d times, want 1", n)
+	}
+}
+
+func TestConnIdleAgainIsConnectedInsteadOfReset(t *testing.T) {
+	ts := startServer(t)
+	l, err := New(1, 1, ts.factory(grpc.WithIdleTimeout(100*time.Millisecond)), nil, WithIdleGrace(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+
+	conn := o.Get()
+	waitState(t, conn, connectivity.Ready)
+	waitState(t, conn, connectivity.Idle)
+
+	o.allowReset()
+	if got := o.Get(); got != conn {
+		t.Fatal("Get replaced the connection after it went idle")
+	}
+
+	eventually(t, "the idle connection to connect", func() bool {
+		return conn.GetState() != connectivity.Idle
+	})
+
+	if n := ts.dialCount(); n != 1 {
+		t.Fatalf("factory called %d times, want 1", n)
+	}
+}
+
+func TestIdleGraceDisabledResetsIdleConn(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 1, WithIdleGrace(0))
+
+	conn := o.Get()
+	o.allowReset()
+	if got := o.Get(); got == conn {
+		t.Fatal("Get kept the idle connection without an idle grace")
+	}
+
+	if n := ts.dialCount(); n != 2 {
+		t.Fatalf("factory called %d times, want 2", n)
+	}
+}
@@ -0,0 +1,26 @@
+package grpclb
+
+import "google.golang.org/grpc"
+
+/*
+GetIndexed selects a connection the same way Get does, select middlewares
+included, and returns it together with the index of its slot. The index is -1
+if no connection could be selected or the connection left the pool before its
+index was looked up.
+*/
+func (o *lb) GetIndexed() (*grpc.ClientConn, int) {
+	conn := o.Get()
+	if conn == nil {
+		return nil, -1
+	}
+
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s, ok := o.bySlot[conn]
+	if !ok {
+		return conn, -1
+	}
+
+	return conn, int(s.index)
+}
@@ -0,0 +1,112 @@
+package grpclb
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// affinityExploreEvery makes every n-th selection ignore latency affinity,
+	// so far connections keep carrying some traffic and stay ready for failover.
+	affinityExploreEvery = 10
+
+	// affinityNearFactor is how many times slower than the fastest connection a
+	// connection may be and still count as near.
+	affinityNearFactor = 2
+)
+
+/*
+near reports whether the slot's connection counts as near the client. Slots
+that have not been measured yet are considered near. The caller must hold the
+mutex.
+*/
+func (o *lb) near(s *slot) bool {
+	return s.rtt == 0 || o.minRTT == 0 || s.rtt <= affinityNearFactor*o.minRTT
+}
+
+/*
+probeLatency measures the round trip time of every connection once per probe
+interval until the load balancer is closed.
+*/
+func (o *lb) probeLatency() {
+	ticker := time.NewTicker(o.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		o.probeAll()
+
+		select {
+		case <-o.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+probeAll measures every connection and records the results, smoothing them
+with the previous measurements.
+*/
+func (o *lb) probeAll() {
+	o.mutex.Lock()
+	slots := make([]*slot, len(o.slots))
+	conns := make([]*grpc.ClientConn, len(o.slots))
+	for i, s := range o.slots {
+		slots[i] = s
+		conns[i] = s.conn
+	}
+	o.unlock()
+
+	rtts := make([]time.Duration, len(slots))
+	for i, conn := range conns {
+		rtts[i] = o.probe(conn)
+	}
+
+	o.mutex.Lock()
+	defer o.unlock()
+
+	for i, s := range slots {
+		if rtts[i] == 0 || s.conn != conns[i] {
+			continue
+		}
+
+		if s.rtt == 0 {
+			s.rtt = rtts[i]
+		} else {
+			s.rtt = (7*s.rtt + 3*rtts[i]) / 10
+		}
+	}
+
+	o.minRTT = 0
+	for _, s := range o.slots {
+		if s.rtt != 0 && (o.minRTT == 0 || s.rtt < o.minRTT) {
+			o.minRTT = s.rtt
+		}
+	}
+}
+
+/*
+probe measures the round trip time of a health check on conn. Any answer from
+the server counts, including Unimplemented, since only the round trip matters.
+It returns 0 if the server could not be reached within the probe interval.
+*/
+func (o *lb) probe(conn *grpc.ClientConn) time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), o.probeInterval)
+	defer cancel()
+
+	start := time.Now()
+	_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	took := time.Since(start)
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return 0
+	}
+
+	return took
+}
@@ -0,0 +1,88 @@
+package grpclb
+
+import (
+	"errors"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+/*
+ErrNoLease is returned by Lease when every connection is already leased.
+*/
+var ErrNoLease = errors.New("no connection available to lease")
+
+/*
+Lease reserves a connection for exclusive use, for example for a transaction
+spanning several RPCs that the backend keys on the connection. Until the
+returned release function is called, the connection is excluded from every
+other selection, including further leases, and counts as in flight, so Resize,
+Swap and Shutdown wait for it like for a request acquired through Acquire. A
+servable connection without requests in flight is preferred. Resets still
+replace a leased connection when it turns unhealthy. release is safe to call
+more than once; only the first call has an effect. ErrNoLease is returned if
+every connection is leased.
+*/
+func (o *lb) Lease() (*grpc.ClientConn, func(), error) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	idle := func(s *slot) bool {
+		return s.inFlight == 0
+	}
+
+	s, err := o.nextWith(nil, func(accept func(s *slot) bool) *slot {
+		if s := o.pick(both(accept, both(idle, o.servable))); s != nil {
+			return s
+		}
+
+		if s := o.pick(both(accept, o.servable)); s != nil {
+			return s
+		}
+
+		return o.pick(accept)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s == nil {
+		return nil, nil, ErrNoLease
+	}
+
+	conn := s.conn
+	s.leased = true
+	s.inFlight++
+	o.leased++
+	o.fastStale = true
+
+	var once sync.Once
+	return conn, func() {
+		once.Do(func() {
+			o.unlease(s, conn)
+		})
+	}, nil
+}
+
+/*
+unleased reports whether the slot is free of a lease.
+*/
+func (o *lb) unleased(s *slot) bool {
+	return !s.leased
+}
+
+/*
+unlease ends the lease of the slot taken while it held conn.
+*/
+func (o *lb) unlease(s *slot, conn *grpc.ClientConn) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s.leased = false
+	o.leased--
+	o.fastStale = true
+	if s = o.holder(s, conn); s.conn == conn && s.inFlight > 0 {
+		s.inFlight--
+		o.notifyReleased()
+	}
+}
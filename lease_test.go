@@ -0,0 +1,32 @@
+package grpclb
+
+import "testing"
+
+func TestGetRoutedSkipsLeasedConns(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 2, WithRouter(func(string) []int {
+		return []int{0}
+	}))
+	connectAll(t, o)
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		_, release, err := o.Lease()
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+
+	if conn := o.GetRouted("tenant"); conn != nil {
+		t.Fatal("GetRouted returned a leased connection")
+	}
+
+	for _, release := range releases {
+		release()
+	}
+
+	if conn := o.GetRouted("tenant"); conn != o.slots[0].conn {
+		t.Fatal("GetRouted did not return the routed connection once the leases ended")
+	}
+}
@@ -0,0 +1,53 @@
+package grpclb
+
+import "google.golang.org/grpc"
+
+/*
+Logger logs a message, like the logger function passed to New.
+*/
+type Logger func(msg string)
+
+/*
+GetWithLogger selects a connection the same way GetHandle does and returns it
+with a logger that prefixes every message with the index and target of the
+connection before passing it to the pool's logger, so request logs show which
+backend served them. The logger is never nil; without a pool logger it discards
+the messages. If no connection could be selected, the connection is nil and the
+logger is not tagged.
+*/
+func (o *lb) GetWithLogger() (*grpc.ClientConn, Logger) {
+	h, err := o.GetHandle()
+	if err != nil {
+		return nil, o.log
+	}
+
+	prefix := "connection " + itoa(h.Index) + " (" + h.Target + "): "
+	return h.Conn, func(msg string) {
+		o.log(prefix + msg)
+	}
+}
+
+/*
+SetLogger replaces the logger passed to New, for example to turn on debug
+logging from an admin endpoint without rebuilding the pool. A nil logger
+disables logging. It is safe to call concurrently with everything else;
+messages being logged while it runs go to either logger.
+*/
+func (o *lb) SetLogger(logger func(msg string)) {
+	o.loggerMutex.Lock()
+	defer o.loggerMutex.Unlock()
+
+	o.logger = logger
+}
+
+/*
+currentLogger returns the logger in use. The logger is guarded by its own
+mutex rather than the pool's, since messages are logged with and without the
+pool's mutex held.
+*/
+func (o *lb) currentLogger() func(msg string) {
+	o.loggerMutex.Lock()
+	defer o.loggerMutex.Unlock()
+
+	return o.logger
+}
@@ -1,29 +1,262 @@
 package grpclb
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
 )
 
+/*
+DefaultMaxSize is the largest pool size accepted by New and Resize unless
+WithMaxSize raises it.
+*/
+const DefaultMaxSize = 4096
+
+/*
+ErrClosed is returned by methods of a load balancer that has been closed.
+*/
+var ErrClosed = errors.New("load balancer is closed")
+
 type LB interface {
 	Get() *grpc.ClientConn
+	GetResult() (Result, error)
+	GetIndexed() (*grpc.ClientConn, int)
+	GetHandle() (Handle, error)
+	GetWithLogger() (*grpc.ClientConn, Logger)
+	SetLogger(logger func(msg string))
+	FailedHandle(h Handle, err error) error
+	GetWithCallOptions() (*grpc.ClientConn, []grpc.CallOption)
+	GetWithCost(cost uint32) *grpc.ClientConn
+	GetWithDeadline(d time.Duration) (*grpc.ClientConn, context.Context, context.CancelFunc)
+	GetContextWithDeadline(parent context.Context, d time.Duration) (*grpc.ClientConn, context.Context, context.CancelFunc)
+	GetExcluding(exclude *grpc.ClientConn) *grpc.ClientConn
+	GetAntiAffinity(previousTags map[string]string) *grpc.ClientConn
+	GetQuorum(r int, distinctBy string) ([]*grpc.ClientConn, error)
+	GetRouted(key string) *grpc.ClientConn
+	GetHealthy() (*grpc.ClientConn, error)
+	DrainConn(ctx context.Context, index uint32) error
+	Call(ctx context.Context, fn func(ctx context.Context, conn *grpc.ClientConn) error, opts ...CallOption) error
+	Hedge(ctx context.Context, delay time.Duration, fn func(ctx context.Context, conn *grpc.ClientConn) (interface{}, error)) (interface{}, error)
+	Acquire(ctx context.Context) (*PooledConn, error)
+	AcquireBytes(ctx context.Context, bytes int64) (*PooledConn, error)
+	TryAcquire() (*PooledConn, bool)
+	Checkout() (*grpc.ClientConn, func())
+	Lease() (*grpc.ClientConn, func(), error)
+	ForceReset() error
+	ResetHistory() []ResetEvent
+	Clone() (LB, error)
+	Report(conn *grpc.ClientConn, err error)
+	ReportTrailer(conn *grpc.ClientConn, trailer metadata.MD, err error)
+	Resize(size uint32) error
+	Reserve(ctx context.Context, n uint32) error
+	Swap(factory func() (*grpc.ClientConn, error), size uint32) error
+	SetPriorities(priorities []int) error
+	SetWeights(weights []uint32) error
+	RecycleConn(index uint32) error
+	Pin(index uint32) error
+	Unpin()
+	Refresh() error
+	UnhealthyIndices() []int
+	RankedConns() []RankedConn
+	Stats() PoolStats
+	ReadyCount() int
+	ReadyChan() <-chan struct{}
+	GroupStats() map[string]PoolStats
+	Config() Config
+	StateDurations() []map[connectivity.State]time.Duration
+	ReadOnly() ReadOnlyLB
+	ClientConn() grpc.ClientConnInterface
+	WaitAllReady(ctx context.Context) error
+	WaitUntilUnhealthy(ctx context.Context, index uint32) error
+	Shutdown(ctx context.Context) error
 	Close() error
 }
 
+type slot struct {
+	uses                uint64
+	conn                *grpc.ClientConn
+	index               uint32
+	inFlight            int64
+	bytesInFlight       int64
+	overflow            bool
+	idleSince           time.Time
+	idleTimer           *time.Timer
+	rtt                 time.Duration
+	stopWatch           context.CancelFunc
+	ready               bool
+	readyPos            int
+	successes           uint64
+	failures            uint64
+	success             float64
+	consecutiveFailures uint32
+	ejections           uint32
+	ejectedUntil        time.Time
+	cost                uint64
+	state               connectivity.State
+	stateSince          time.Time
+	recovering          bool
+	lastRecovery        time.Time
+	usable              bool
+	recoveredAt         time.Time
+	paused              bool
+	unservableSince     time.Time
+	failing             bool
+	createdAt           time.Time
+	proven              bool
+	leased              bool
+	queued              bool
+	nudgedAt            time.Time
+	utilization         float64
+	reportedAt          time.Time
+	readySince          time.Time
+	pickedAt            time.Time
+	lastState           connectivity.State
+	durations           map[connectivity.State]time.Duration
+}
+
+/*
+Result describes a single selection made by GetResult: the connection, the
+index of its slot in the pool and whether the call had to reset the
+connections before selecting.
+*/
+type Result struct {
+	Conn  *grpc.ClientConn
+	Index uint32
+	Reset bool
+}
+
 type lb struct {
-	conns                   []*grpc.ClientConn
+	fastNext                uint64
+	fastUseCount            uint64
+	fast                    atomic.Value
+	fastStale               bool
+	slots                   []*slot
 	size                    uint32
 	offset                  uint32
 	factory                 func() (*grpc.ClientConn, error)
 	mutex                   sync.Mutex
 	lastReset               time.Time
+	probing                 bool
+	resetsWaited            uint64
 	minRetryIntervalSeconds uint32
 	logger                  func(msg string)
 	useCount                uint64
+	name                    string
+	stride                  uint32
+	maxInFlight             int64
+	released                chan struct{}
+	waitForReady            bool
+	resetBatch              uint32
+	resetBatchDelay         time.Duration
+	resetCall               *resetCall
+	closed                  bool
+	overflow                []*slot
+	maxOverflow             uint32
+	overflowIdleTimeout     time.Duration
+	usable                  func(state connectivity.State) bool
+	parked                  []*slot
+	resizeGrace             time.Duration
+	middlewares             []SelectMiddleware
+	get                     func() *grpc.ClientConn
+	maxSize                 uint32
+	done                    chan struct{}
+	latencyAffinity         bool
+	probeInterval           time.Duration
+	minRTT                  time.Duration
+	validator               func(conn *grpc.ClientConn) bool
+	priorities              []int
+	groups                  []string
+	tags                    []map[string]string
+	lastSuccessfulReset     time.Time
+	lastFailedReset         time.Time
+	history                 []ResetEvent
+	historyNext             int
+	historySize             int
+	spillover               float64
+	maxConcurrentResets     uint32
+	shadowFactory           func() (*grpc.ClientConn, error)
+	shadowFraction          float64
+	shadow                  *grpc.ClientConn
+	pin                     uint32
+	pinned                  bool
+	pinLogged               time.Time
+	pinPicks                uint32
+	events                  *eventWriter
+	tieBreaker              TieBreaker
+	onRemove                func(index int, reason string)
+	onReadd                 func(index int)
+	resetAttempts           []uint32
+	idleGrace               time.Duration
+	orca                    bool
+	shrinkPolicy            ShrinkPolicy
+	slowStart               time.Duration
+	redialing               uint32
+	recoverQueue            []*slot
+	readySet                bool
+	stateDurations          bool
+	preferRecovered         time.Duration
+	ready                   []*slot
+	readyOffset             uint32
+	dialSpread              time.Duration
+	store                   *ConnStore
+	storeKey                string
+	shared                  bool
+	rand                    *rand.Rand
+	bySlot                  map[*grpc.ClientConn]*slot
+	outlier                 *OutlierConfig
+	strategy                Strategy
+	weights                 []uint32
+	weightsSet              bool
+	cumWeights              []uint64
+	opts                    []Option
+	autoReset               bool
+	asyncReset              bool
+	perSlotRecovery         bool
+	paused                  int
+	window                  uint32
+	windowRotate            time.Duration
+	windowEpoch             time.Time
+	drainTimeout            time.Duration
+	recoveryGrace           time.Duration
+	coordinator             *ResetCoordinator
+	poolMaxAge              time.Duration
+	indexMetadata           string
+	router                  func(routingKey string) []int
+	failFast                time.Duration
+	retryable               func(err error) bool
+	fatal                   error
+	capacityThreshold       float64
+	onCapacity              func(readyFraction float64)
+	degraded                bool
+	resetWait               time.Duration
+	dialMutex               sync.Mutex
+	loggerMutex             sync.Mutex
+	dialStats               DialStats
+	dialTotal               time.Duration
+	slowDial                time.Duration
+	onSlowDial              func(index int, took time.Duration)
+	cooldown                time.Duration
+	resetDeadline           time.Duration
+	leased                  int
+	target                  string
+	dialOptions             []grpc.DialOption
+	compressor              string
+	readyCh                 chan struct{}
+	trackReady              bool
+	goAwayRefresh           bool
+	retiring                map[*grpc.ClientConn]*slot
+	unreadySince            time.Time
+	onReset                 func(indices []int)
+	pending                 []func()
 }
 
 /*
@@ -31,9 +264,12 @@ New creates a new load balancer with the given factory function and size.
 The factory function is used to create the connections that the load balancer
 will manage. The size parameter determines how many connections the load
 balancer will manage. The factory function must return a new connection each
-time it is called. The size parameter must be greater than 0.
+time it is called. The size parameter must be greater than 0 and at most
+DefaultMaxSize, or the limit set with WithMaxSize, so that a mistyped size
+fails cleanly instead of exhausting memory. Additional behaviour can be
+configured with options such as WithName.
 */
-func New(size uint32, minRetryIntervalSeconds uint32, factory func() (*grpc.ClientConn, error), logger func(msg string)) (LB, error) {
+func New(size uint32, minRetryIntervalSeconds uint32, factory func() (*grpc.ClientConn, error), logger func(msg string), opts ...Option) (LB, error) {
 	switch {
 	case factory == nil:
 		return nil, errors.New("factory can't be nil3")
@@ -43,18 +279,7 @@ func New(size uint32, minRetryIntervalSeconds uint32, factory func() (*grpc.Clie
 		return nil, errors.New("minRetryIntervalSeconds must be greater than 0")
 	}
 
-	conns := make([]*grpc.ClientConn, size)
-	for i := uint32(0); i < size; i++ {
-		conn, err := factory()
-		if err != nil {
-			return nil, err
-		}
-
-		conns[i] = conn
-	}
-
-	return &lb{
-		conns:                   conns,
+	o := &lb{
 		size:                    size,
 		offset:                  0,
 		factory:                 factory,
@@ -63,7 +288,101 @@ func New(size uint32, minRetryIntervalSeconds uint32, factory func() (*grpc.Clie
 		minRetryIntervalSeconds: minRetryIntervalSeconds,
 		logger:                  logger,
 		useCount:                0,
-	}, nil
+		stride:                  1,
+		released:                make(chan struct{}),
+		usable:                  isReady,
+		maxSize:                 DefaultMaxSize,
+		done:                    make(chan struct{}),
+		readyCh:                 make(chan struct{}),
+		rand:                    rand.New(rand.NewSource(time.Now().UnixNano())),
+		bySlot:                  make(map[*grpc.ClientConn]*slot),
+		opts:                    opts,
+		autoReset:               true,
+		historySize:             DefaultResetHistorySize,
+		idleGrace:               DefaultIdleGrace,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	if size > o.maxSize {
+		return nil, fmt.Errorf("size %d exceeds the maximum of %d", size, o.maxSize)
+	}
+
+	if gcd(o.stride, size) != 1 {
+		return nil, errors.New("stride must be coprime with size")
+	}
+
+	o.get = o.selectConn
+	for i := len(o.middlewares) - 1; i >= 0; i-- {
+		o.get = o.middlewares[i](o.get)
+	}
+
+	if o.priorities != nil && len(o.priorities) != int(size) {
+		return nil, errors.New("priorities must have one entry per connection")
+	}
+
+	if o.weights != nil && len(o.weights) != int(size) {
+		return nil, errors.New("weights must have one entry per connection")
+	}
+
+	if o.groups != nil && len(o.groups) != int(size) {
+		return nil, errors.New("groups must have one entry per connection")
+	}
+
+	if o.tags != nil && len(o.tags) != int(size) {
+		return nil, errors.New("tags must have one entry per connection")
+	}
+
+	if o.resetAttempts != nil && len(o.resetAttempts) != int(size) {
+		return nil, errors.New("reset attempts must have one entry per connection")
+	}
+
+	if o.strategy != RoundRobin && o.weights == nil {
+		o.weights = make([]uint32, size)
+		for i := range o.weights {
+			o.weights[i] = 1
+		}
+	}
+	o.updateWeights()
+
+	conns, err := o.dialAll(size)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.failFast > 0 {
+		if err := o.probeReady(conns[0]); err != nil {
+			o.closeAll(conns)
+			return nil, err
+		}
+	}
+
+	if err := o.dialShadow(); err != nil {
+		o.closeAll(conns)
+		return nil, err
+	}
+
+	o.slots = make([]*slot, size)
+	for i, conn := range conns {
+		o.slots[i] = o.newSlot(uint32(i), conn)
+	}
+
+	if o.latencyAffinity {
+		go o.probeLatency()
+	}
+
+	if o.poolMaxAge > 0 {
+		go o.refreshEvery()
+	}
+
+	o.publish()
+	return o, nil
 }
 
 /*
@@ -71,65 +390,715 @@ Get returns the next connection managed by the load balancer. The connections
 are returned in a round-robin fashion. If a connection is not ready, the next
 connection is returned. If all connections are not ready, the connections are
 reset and the first connection is returned. If the connections fail to reset,
-nil is returned.
+nil is returned. Select middlewares configured with WithSelectMiddleware wrap
+the selection. With plain round-robin selection, a call that lands on a usable
+connection does not take the pool's mutex.
 */
 func (o *lb) Get() *grpc.ClientConn {
+	return o.get()
+}
+
+/*
+selectConn is the core selection behind Get, before any select middleware is
+applied.
+*/
+func (o *lb) selectConn() *grpc.ClientConn {
+	if conn := o.fastGet(); conn != nil {
+		return conn
+	}
+
 	o.mutex.Lock()
-	defer o.mutex.Unlock()
+	defer o.unlock()
 
-	conn := o.conns[o.offset]
+	s, err := o.next(nil)
+	if err != nil || s == nil {
+		return nil
+	}
 
-	if conn.GetState() != connectivity.Ready && o.useCount > uint64(o.offset) {
-		if time.Now().UTC().Sub(o.lastReset) > time.Duration(o.minRetryIntervalSeconds)*time.Second {
-			o.lastReset = time.Now().UTC()
-			if err := o.reset(); err != nil {
-				if o.logger != nil {
-					o.logger("Failed to reset connections: " + err.Error())
+	return s.conn
+}
+
+/*
+GetResult selects a connection the same way Get does and reports the details
+of the selection. Reset is true when this call paid the cost of a synchronous
+reset, or waited for a background reset with WithResetWait, which helps
+attributing slow requests to reconnection events. Merely starting a background
+reset does not count. If the reset fails, its error is returned.
+*/
+func (o *lb) GetResult() (Result, error) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	waited := o.resetsWaited
+	s, err := o.next(nil)
+	reset := o.resetsWaited != waited
+	if err != nil {
+		return Result{Reset: reset}, err
+	}
+
+	if s == nil {
+		return Result{Reset: reset}, errNoConn
+	}
+
+	return Result{Conn: s.conn, Index: s.index, Reset: reset}, nil
+}
+
+/*
+GetWithCallOptions selects a connection the same way Get does and returns it
+together with the call options that match the pool's configuration. With
+WithWaitForReady this includes grpc.WaitForReady(true), so RPCs on a
+connection that is not ready yet are queued by gRPC instead of failing fast.
+*/
+func (o *lb) GetWithCallOptions() (*grpc.ClientConn, []grpc.CallOption) {
+	conn := o.Get()
+	if o.waitForReady {
+		return conn, []grpc.CallOption{grpc.WaitForReady(true)}
+	}
+
+	return conn, nil
+}
+
+/*
+next selects the slot to hand out. If the pool needs recovery, see
+needsRecovery, the connections are reset, subject to the minimum retry
+interval, unless WithWaitForReady is set or WithAutoReset disabled it, and the
+reset error is returned if that fails. The slot is then picked among the ones
+accepted by accept, see pick; a nil accept accepts every slot. Leased slots are
+never picked. Slots ejected by outlier detection are skipped and slots draining
+through DrainConn are only picked if no other slot is accepted. A slot pinned
+with Pin is picked whenever it is servable and accepted. With priorities, only
+the best tier with a servable slot is considered. Without automatic reset,
+servable slots are preferred; with WithPerSlotRecovery they are preferred too
+and the slots found unservable on the way are redialed in the background. If no
+slot is accepted, nil is returned with a nil error. ErrClosed is returned once
+the load balancer has been closed. The caller must hold the mutex.
+*/
+func (o *lb) next(accept func(s *slot) bool) (*slot, error) {
+	return o.nextWith(accept, o.pick)
+}
+
+/*
+nextWith is next with a custom picker in place of pick. The caller must hold
+the mutex.
+*/
+func (o *lb) nextWith(accept func(s *slot) bool, pick func(accept func(s *slot) bool) *slot) (*slot, error) {
+	if o.closed {
+		return nil, ErrClosed
+	}
+
+	if o.leased > 0 {
+		accept = both(accept, o.unleased)
+	}
+
+	if s := o.pinnedSlot(accept); s != nil {
+		o.count(s)
+		return s, nil
+	}
+
+	if o.windowed() {
+		pick = o.pickWindowed(pick)
+	}
+
+	if o.cooldown > 0 {
+		pickAny := pick
+		pick = func(accept func(s *slot) bool) *slot {
+			if s := pickAny(both(accept, o.settled)); s != nil {
+				return s
+			}
+
+			return pickAny(accept)
+		}
+	}
+
+	if o.slowStart > 0 {
+		pickAny := pick
+		pick = func(accept func(s *slot) bool) *slot {
+			if warm := o.warm(); warm != nil {
+				if s := pickAny(both(accept, warm)); s != nil {
+					return s
 				}
-				return nil
 			}
 
-			conn = o.conns[o.offset]
+			return pickAny(accept)
+		}
+	}
+
+	if o.paused > 0 {
+		pickAny := pick
+		pick = func(accept func(s *slot) bool) *slot {
+			if s := pickAny(both(accept, o.active)); s != nil {
+				return s
+			}
+
+			return pickAny(accept)
+		}
+	}
+
+	if o.autoReset && !o.waitForReady && !o.perSlotRecovery && o.resetCall == nil && o.needsRecovery() {
+		if o.fatal != nil {
+			return nil, o.fatal
+		}
+
+		if time.Now().UTC().Sub(o.lastReset) > time.Duration(o.minRetryIntervalSeconds)*time.Second && o.admitPoolReset() {
+			o.lastReset = time.Now().UTC()
+			if o.asyncReset {
+				o.startReset()
+			} else if err := o.reset(ResetAuto); err != nil {
+				o.log("Failed to reset connections: " + err.Error())
+				return nil, err
+			}
+		}
+	}
+
+	if call := o.resetCall; call != nil && o.asyncReset && o.resetWait > 0 && o.needsRecovery() {
+		o.awaitReset(call)
+		if o.closed {
+			return nil, ErrClosed
+		}
+	}
+
+	if o.outlier != nil {
+		now := time.Now()
+		accept = both(accept, func(s *slot) bool {
+			return !o.ejected(s, now)
+		})
+	}
+
+	if o.priorities != nil {
+		if tier, ok := o.bestTier(); ok {
+			tier = o.spill(tier)
+			accept = both(accept, func(s *slot) bool {
+				return o.priorities[s.index] == tier
+			})
+		}
+	}
+
+	if o.recoversPerSlot() {
+		if s := pick(both(accept, o.servableOrRecover)); s != nil {
+			return s, nil
+		}
+	} else if !o.autoReset {
+		if s := pick(both(accept, o.servable)); s != nil {
+			return s, nil
+		}
+	}
+
+	return pick(accept), nil
+}
+
+/*
+pick selects a slot accepted by accept according to the strategy: sampled by
+weight with WeightedRandom, by load with WeightedLeastConnections, otherwise
+round-robin, preferring slots near the client with WithLatencyAffinity. With
+WithPreferRecovered, recently recovered slots get a share of the selections
+first. It returns nil if no slot is accepted. The caller must hold the mutex.
+*/
+func (o *lb) pick(accept func(s *slot) bool) *slot {
+	if s := o.pickRecovered(accept); s != nil {
+		return s
+	}
+
+	if o.orca {
+		if s := o.pickLeastUtilized(accept); s != nil {
+			return s
+		}
+	}
+
+	switch o.strategy {
+	case WeightedRandom:
+		if s := o.pickWeightedRandom(accept); s != nil {
+			return s
+		}
+	case WeightedLeastConnections:
+		if s := o.pickLeastLoaded(accept); s != nil {
+			return s
+		}
+	case LeastBytesInFlight:
+		if s := o.pickLeastBytes(accept); s != nil {
+			return s
+		}
+	}
+
+	if o.latencyAffinity && o.useCount%affinityExploreEvery != affinityExploreEvery-1 {
+		near := func(s *slot) bool {
+			return o.near(s) && (accept == nil || accept(s))
+		}
+
+		if s := o.scan(near); s != nil {
+			return s
+		}
+	}
+
+	return o.scan(accept)
+}
+
+/*
+needsRecovery reports whether selection has to reset the connections. Without
+a ready set that is the case when the connection at the current offset is not
+servable although it has been handed out before, which gave a lazily
+connecting connection the chance to connect; with a ready set, when the set is
+empty. Either has to persist for the grace period set by WithRecoveryGrace.
+Uses through the fast path count, so a single connection that fails after
+serving only lock-free selections is recovered on the next call like any
+other. An Idle connection is asked to connect and given the grace period set by
+WithIdleGrace instead. The caller must hold the mutex.
+*/
+func (o *lb) needsRecovery() bool {
+	if o.readySet {
+		return o.pastGrace(&o.unreadySince, len(o.ready) == 0)
+	}
+
+	s := o.slots[o.offset]
+	unservable := !o.servable(s)
+	if !unservable {
+		s.nudgedAt = time.Time{}
+	} else if o.nudged(s) {
+		unservable = false
+	}
+
+	return o.pastGrace(&s.unservableSince, unservable) && atomic.LoadUint64(&s.uses) > 0
+}
+
+/*
+both returns a filter accepting the slots accepted by a and b. A nil filter
+accepts every slot.
+*/
+func both(a, b func(s *slot) bool) func(s *slot) bool {
+	if a == nil {
+		return b
+	}
+
+	return func(s *slot) bool {
+		return a(s) && b(s)
+	}
+}
+
+/*
+scan walks the slots in round-robin order from the current offset and returns
+the first one accepted by accept, or nil after a full cycle. With a ready set,
+the ready slots are walked first. The caller must hold the mutex.
+*/
+func (o *lb) scan(accept func(s *slot) bool) *slot {
+	if s := o.scanReady(accept); s != nil {
+		return s
+	}
+
+	for i := uint32(0); i < o.size; i++ {
+		s := o.slots[o.offset]
+		o.offset = uint32((uint64(o.offset) + uint64(o.stride)) % uint64(o.size))
+		if accept == nil || accept(s) {
+			o.count(s)
+			return s
 		}
 	}
 
-	o.offset = (o.offset + 1) % o.size
+	return nil
+}
+
+/*
+count records that the slot was selected, unless the selection is only probing
+candidates. The caller must hold the mutex.
+*/
+func (o *lb) count(s *slot) {
+	if o.probing {
+		return
+	}
+
 	o.useCount++
-	return conn
+	atomic.AddUint64(&s.uses, 1)
+	if o.goAwayRefresh {
+		s.pickedAt = time.Now()
+	}
+}
+
+/*
+ForceReset closes all the connections managed by the load balancer and creates
+new ones immediately, ignoring the minimum retry interval. It is meant as a
+manual recovery hatch for when the backend is known to be healthy again. The
+time of the reset is recorded, so automatic resets are throttled from this
+point on. If a reset is already in progress, ForceReset waits for it and
+returns its result instead of starting another one.
+*/
+func (o *lb) ForceReset() error {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return ErrClosed
+	}
+
+	if call := o.resetCall; call != nil {
+		return o.joinReset(call)
+	}
+
+	o.lastReset = time.Now().UTC()
+	return o.reset(ResetForced)
+}
+
+/*
+RecycleConn closes the connection at the given index and replaces it with a new
+one, leaving the other connections untouched. Together with UnhealthyIndices it
+lets an operator repair exactly the connections that are misbehaving.
+*/
+func (o *lb) RecycleConn(index uint32) error {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return ErrClosed
+	}
+
+	if index >= o.size {
+		return fmt.Errorf("index %d out of range for pool of size %d", index, o.size)
+	}
+
+	start := time.Now()
+	err := o.resetConn(o.slots[index])
+	o.recordReset(ResetRecycle, start, []int{int(index)}, err)
+	if err != nil {
+		return err
+	}
+
+	o.notifyReset([]int{int(index)})
+	o.notifyReleased()
+	return nil
+}
+
+/*
+Clone creates a new load balancer with the same configuration as this one: the
+current factory function and size, the options it was created with and the
+current priorities and weights. The clone dials its own connections and shares
+no state with the original, except for state captured by the options
+themselves, such as a SelectionCounter passed to WithSelectMiddleware. A
+ConnStore set with WithConnStore is such state: the clone obtains its
+connections through the store like any other pool using the same key, so it
+shares the original's connections. Each pool holds its own reference to them,
+and closing one of the two leaves them open for the other.
+*/
+func (o *lb) Clone() (LB, error) {
+	o.mutex.Lock()
+	if o.closed {
+		o.unlock()
+		return nil, ErrClosed
+	}
+
+	factory, size := o.factory, o.size
+	opts := append([]Option(nil), o.opts...)
+	if o.priorities != nil {
+		opts = append(opts, WithPriorities(o.priorities))
+	}
+
+	if o.weightsSet || o.usesWeights() {
+		opts = append(opts, WithWeights(o.weights))
+	}
+
+	if o.groups != nil {
+		opts = append(opts, WithGroups(o.groups))
+	}
+
+	if o.tags != nil {
+		opts = append(opts, WithTags(o.tags))
+	}
+
+	if o.resetAttempts != nil {
+		opts = append(opts, WithSlotResetAttempts(o.resetAttempts))
+	}
+	o.unlock()
+
+	return New(size, o.minRetryIntervalSeconds, factory, o.currentLogger(), opts...)
 }
 
 /*
-Close closes all the connections managed by the load balancer. If any of the
-connections fail to close, an error is returned.
+Close closes all the connections managed by the load balancer. Every connection
+is closed even if some of them fail, and the first error is returned. Once
+closed, Get returns nil, Acquire, GetResult and ForceReset return ErrClosed and
+pending Acquire calls are woken up. Closing an already closed load balancer is
+a no-op.
 */
 func (o *lb) Close() error {
-	for _, conn := range o.conns {
-		if err := conn.Close(); err != nil {
-			return err
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	o.markClosed()
+	return o.closeConns()
+}
+
+/*
+markClosed marks the load balancer as closed and wakes up everything waiting on
+it. The caller must hold the mutex.
+*/
+func (o *lb) markClosed() {
+	o.closed = true
+	close(o.done)
+	o.notifyReleased()
+}
+
+/*
+closeConns closes every connection of the pool, including overflow, parked and
+shadow connections, and returns the first error. The caller must hold the
+mutex.
+*/
+func (o *lb) closeConns() error {
+	var firstErr error
+	for _, s := range o.slots {
+		o.detach(s)
+		if err := o.closeConn(s.conn); err != nil && firstErr == nil {
+			firstErr = err
 		}
 	}
 
-	return nil
+	for _, s := range o.overflow {
+		if s.idleTimer != nil {
+			s.idleTimer.Stop()
+		}
+
+		if err := o.closeConn(s.conn); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	o.overflow = nil
+
+	for _, s := range o.parked {
+		s.idleTimer.Stop()
+		if err := o.closeConn(s.conn); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	o.parked = nil
+
+	if o.shadow != nil {
+		if err := o.shadow.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+/*
+servable reports whether the slot's connection may be handed out: its state
+must be usable and it must pass the validator set with WithValidator, if any.
+The caller must hold the mutex.
+*/
+func (o *lb) servable(s *slot) bool {
+	if !o.usable(s.conn.GetState()) {
+		return false
+	}
+
+	return o.validator == nil || o.validator(s.conn)
+}
+
+/*
+isReady is the default usability predicate: only Ready connections are
+considered fully healthy.
+*/
+func isReady(state connectivity.State) bool {
+	return state == connectivity.Ready
+}
+
+/*
+unlock releases the mutex and then runs the callbacks queued while it was held,
+so user callbacks never run under the mutex and may call back into the load
+balancer.
+*/
+func (o *lb) unlock() {
+	if o.fastStale {
+		o.publish()
+	}
+
+	pending := o.pending
+	o.pending = nil
+	o.mutex.Unlock()
+
+	for _, fn := range pending {
+		fn()
+	}
+}
+
+/*
+notifyReset queues the WithOnReset callback for the given recreated slots. The
+caller must hold the mutex.
+*/
+func (o *lb) notifyReset(indices []int) {
+	if o.onReset == nil {
+		return
+	}
+
+	onReset := o.onReset
+	o.pending = append(o.pending, func() {
+		onReset(indices)
+	})
+}
+
+/*
+gcd returns the greatest common divisor of a and b.
+*/
+func gcd(a, b uint32) uint32 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+/*
+itoa formats an index for log messages.
+*/
+func itoa(i uint32) string {
+	return strconv.FormatUint(uint64(i), 10)
+}
+
+/*
+log passes msg to the logger, if one is configured, prefixed with the pool
+name when the pool has one. It may be called with or without the mutex held.
+*/
+func (o *lb) log(msg string) {
+	logger := o.currentLogger()
+	if logger == nil {
+		return
+	}
+
+	if o.name != "" {
+		msg = "[" + o.name + "] " + msg
+	}
+
+	logger(msg)
 }
 
 /*
 Reset closes all the connections managed by the load balancer and creates new
 connections using the factory function. If any of the connections fail to close
-or if any of the new connections fail to be created, an error is returned.
+or if any of the new connections fail to be created, an error is returned. With
+WithResetBatch the connections are recreated in batches and the mutex is
+released during the delay between batches, so the pool keeps serving from the
+connections that have not been reset yet. With WithResetDeadline the
+replacements are dialed first and installed together, see resetWithin. Only one
+reset is in flight at a time: Get does not start another one meanwhile and
+ForceReset joins it. The outcome is recorded as the last successful or last
+failed reset and in the reset history under trigger. The caller must hold the
+mutex.
 */
-func (o *lb) reset() error {
+func (o *lb) reset(trigger ResetTrigger) (err error) {
+	o.resetsWaited++
+	call := o.beginReset(trigger)
+	defer func() {
+		o.endReset(call, err)
+		if err != nil {
+			o.lastFailedReset = time.Now().UTC()
+		} else {
+			o.lastSuccessfulReset = time.Now().UTC()
+		}
+	}()
+
+	if o.resetDeadline > 0 {
+		return o.resetWithin(time.Now().Add(o.resetDeadline))
+	}
+
 	for i := uint32(0); i < o.size; i++ {
-		if err := o.conns[i].Close(); err != nil {
+		if o.resetBatch > 0 && i > 0 && i%o.resetBatch == 0 {
+			o.mutex.Unlock()
+			time.Sleep(o.resetBatchDelay)
+			o.mutex.Lock()
+
+			if o.closed {
+				return ErrClosed
+			}
+		}
+
+		if err := o.resetConn(o.slots[i]); err != nil {
 			return err
 		}
+	}
 
-		conn, err := o.factory()
-		if err != nil {
+	o.notifyReset(allIndices(o.size))
+	o.notifyReleased()
+
+	return nil
+}
+
+/*
+dial creates a new connection for the slot at index with the factory
+function, recording the time the factory took in the dial stats. A factory that
+returns a nil connection, or one that is already shut down, is treated as
+having failed, so such connections never end up in the pool. With
+WithConnStore a connection shared with other pools is reused when possible.
+*/
+func (o *lb) dial(index uint32) (*grpc.ClientConn, error) {
+	if o.shared {
+		return o.store.acquire(o, o.storeKey, o.timed(index, o.factory))
+	}
+
+	return dialWith(o.timed(index, o.factory))
+}
+
+/*
+redial creates a fresh connection to replace an unhealthy one. Unlike dial it
+never reuses a connection shared through a store.
+*/
+func (o *lb) redial(index uint32) (*grpc.ClientConn, error) {
+	return o.redialer(index)()
+}
+
+/*
+redialer returns a function that redials the connection at index with the
+current factory, within the slot's reset attempt budget, for use after the
+mutex has been released. The caller must hold the mutex.
+*/
+func (o *lb) redialer(index uint32) func() (*grpc.ClientConn, error) {
+	factory := o.withAttempts(index, o.timed(index, o.factory))
+	if o.shared {
+		store, key := o.store, o.storeKey
+		return func() (*grpc.ClientConn, error) {
+			return store.add(o, key, factory)
+		}
+	}
+
+	return func() (*grpc.ClientConn, error) {
+		return dialWith(factory)
+	}
+}
+
+/*
+dialWith is dial for an arbitrary factory function.
+*/
+func dialWith(factory func() (*grpc.ClientConn, error)) (*grpc.ClientConn, error) {
+	conn, err := factory()
+	switch {
+	case err != nil:
+		return nil, err
+	case conn == nil:
+		return nil, errors.New("factory returned a nil connection")
+	case conn.GetState() == connectivity.Shutdown:
+		return nil, errors.New("factory returned a closed connection")
+	}
+
+	return conn, nil
+}
+
+/*
+resetConn closes the connection of a single slot and replaces it with a new one
+created by the factory function. A connection already closed by an earlier
+reset that failed to dial its replacement is not closed again. The caller must
+hold the mutex.
+*/
+func (o *lb) resetConn(s *slot) error {
+	o.detach(s)
+	if s.conn.GetState() != connectivity.Shutdown {
+		if err := o.discard(s.conn); err != nil {
 			return err
 		}
+	}
 
-		o.conns[i] = conn
+	conn, err := o.redial(s.index)
+	if err != nil {
+		return err
 	}
 
+	o.attach(s, conn)
+	s.inFlight = 0
 	return nil
 }
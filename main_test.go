@@ -0,0 +1,68 @@
+package grpclb
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestFactoryReturningUnusableConn(t *testing.T) {
+	ts := startServer(t)
+
+	closed := func() (*grpc.ClientConn, error) {
+		conn, err := ts.factory()()
+		if err != nil {
+			return nil, err
+		}
+
+		return conn, conn.Close()
+	}
+
+	for _, c := range []struct {
+		name    string
+		factory func() (*grpc.ClientConn, error)
+		want    string
+	}{
+		{"closed", closed, "factory returned a closed connection"},
+		{"nil", func() (*grpc.ClientConn, error) { return nil, nil }, "factory returned a nil connection"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := New(2, 1, c.factory, nil); err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("New: got error %v, want %q", err, c.want)
+			}
+
+			var broken int32
+			var returned atomic.Value
+			dial := ts.factory()
+			l, err := New(2, 1, func() (*grpc.ClientConn, error) {
+				if atomic.LoadInt32(&broken) == 0 {
+					return dial()
+				}
+
+				conn, err := c.factory()
+				returned.Store(conn)
+				return conn, err
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			atomic.StoreInt32(&broken, 1)
+			if err := l.RecycleConn(0); err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("RecycleConn: got error %v, want %q", err, c.want)
+			}
+
+			o := l.(*lb)
+			o.mutex.Lock()
+			defer o.unlock()
+			for _, s := range o.slots {
+				if s.conn == nil || s.conn == returned.Load() {
+					t.Error("the unusable connection ended up in the pool")
+				}
+			}
+		})
+	}
+}
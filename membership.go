@@ -0,0 +1,68 @@
+package grpclb
+
+import "time"
+
+/*
+WithOnRemove registers a callback that runs whenever a connection leaves the
+rotation, with its index and the reason: "consecutive_failures" or
+"success_rate" when outlier detection ejects it, "shrink" when Resize removes
+it. Ejected connections return after their ejection time, see WithOnReadd. The
+callback runs without the pool's mutex held.
+*/
+func WithOnRemove(onRemove func(index int, reason string)) Option {
+	return func(o *lb) {
+		o.onRemove = onRemove
+	}
+}
+
+/*
+WithOnReadd registers a callback that runs with the index of a connection
+ejected by outlier detection once its ejection time is over and it is back in
+rotation. It does not run if the connection was replaced in the meantime. The
+callback runs without the pool's mutex held.
+*/
+func WithOnReadd(onReadd func(index int)) Option {
+	return func(o *lb) {
+		o.onReadd = onReadd
+	}
+}
+
+/*
+notifyRemove queues the WithOnRemove callback for the slot. The caller must
+hold the mutex.
+*/
+func (o *lb) notifyRemove(s *slot, reason string) {
+	if o.onRemove == nil {
+		return
+	}
+
+	onRemove, index := o.onRemove, int(s.index)
+	o.pending = append(o.pending, func() {
+		onRemove(index, reason)
+	})
+}
+
+/*
+scheduleReadd arranges for the WithOnReadd callback to run once the ejection
+of the slot's current connection ends. The caller must hold the mutex.
+*/
+func (o *lb) scheduleReadd(s *slot) {
+	if o.onReadd == nil {
+		return
+	}
+
+	conn, until := s.conn, s.ejectedUntil
+	time.AfterFunc(time.Until(until), func() {
+		o.mutex.Lock()
+		defer o.unlock()
+
+		if o.closed || o.bySlot[conn] != s || !s.ejectedUntil.Equal(until) {
+			return
+		}
+
+		onReadd, index := o.onReadd, int(s.index)
+		o.pending = append(o.pending, func() {
+			onReadd(index)
+		})
+	})
+}
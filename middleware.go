@@ -0,0 +1,77 @@
+package grpclb
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+/*
+SelectMiddleware wraps the connection selection performed by Get. It receives
+the next selection function in the chain and returns a function that may run
+logic before or after calling it, or replace its result altogether.
+*/
+type SelectMiddleware func(next func() *grpc.ClientConn) func() *grpc.ClientConn
+
+/*
+WithSelectMiddleware wraps Get with the given middlewares. Middlewares compose
+in the order they are given, across repeated uses of the option as well: the
+first middleware is the outermost one and runs first.
+*/
+func WithSelectMiddleware(middlewares ...SelectMiddleware) Option {
+	return func(o *lb) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+/*
+SelectionCounter is a select middleware that counts how many times each
+connection has been selected. It doubles as an example of writing a middleware:
+
+	counter := grpclb.NewSelectionCounter()
+	lb, err := grpclb.New(size, interval, factory, logger,
+		grpclb.WithSelectMiddleware(counter.Middleware))
+*/
+type SelectionCounter struct {
+	mutex  sync.Mutex
+	counts map[*grpc.ClientConn]uint64
+}
+
+/*
+NewSelectionCounter creates a SelectionCounter with no recorded selections.
+*/
+func NewSelectionCounter() *SelectionCounter {
+	return &SelectionCounter{counts: make(map[*grpc.ClientConn]uint64)}
+}
+
+/*
+Middleware is the SelectMiddleware that records every connection returned by
+next. Failed selections, which return nil, are not counted.
+*/
+func (c *SelectionCounter) Middleware(next func() *grpc.ClientConn) func() *grpc.ClientConn {
+	return func() *grpc.ClientConn {
+		conn := next()
+		if conn != nil {
+			c.mutex.Lock()
+			c.counts[conn]++
+			c.mutex.Unlock()
+		}
+
+		return conn
+	}
+}
+
+/*
+Counts returns a copy of the number of selections recorded per connection.
+*/
+func (c *SelectionCounter) Counts() map[*grpc.ClientConn]uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	counts := make(map[*grpc.ClientConn]uint64, len(c.counts))
+	for conn, n := range c.counts {
+		counts[conn] = n
+	}
+
+	return counts
+}
@@ -0,0 +1,212 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+Option configures optional behaviour of the load balancer. Options are passed
+to New after the required arguments.
+*/
+type Option func(*lb)
+
+/*
+WithName sets the name of the pool. The name prefixes every message passed to
+the logger, which makes the output of several load balancers in the same
+process distinguishable. By default the pool has no name and messages are
+logged unprefixed.
+*/
+func WithName(name string) Option {
+	return func(o *lb) {
+		o.name = name
+	}
+}
+
+/*
+WithStride makes the round-robin offset advance by step connections on every
+selection instead of one. When the pool is laid out in groups, for example the
+first half dialed to zone A and the second half to zone B, a stride close to
+half the size interleaves selections across the groups rather than draining
+them in order; a pool of 5 with a stride of 3 visits 0, 3, 1, 4, 2. The step
+must be coprime with the pool size so that every connection is still visited;
+New returns an error otherwise.
+*/
+func WithStride(step uint32) Option {
+	return func(o *lb) {
+		o.stride = step
+	}
+}
+
+/*
+WithMaxInFlight limits the number of requests acquired through Acquire and
+TryAcquire that may be in flight on a single connection at the same time. Once
+every connection reaches the limit, Acquire blocks and TryAcquire fails until a
+PooledConn is released. Get is not subject to the limit. A limit of 0, the
+default, means no limit.
+*/
+func WithMaxInFlight(n uint32) Option {
+	return func(o *lb) {
+		o.maxInFlight = int64(n)
+	}
+}
+
+/*
+WithWaitForReady aligns the pool with gRPC's wait-for-ready semantics. The pool
+no longer resets connections that are not ready, since RPCs issued with
+grpc.WaitForReady(true) are queued by gRPC until the channel recovers on its
+own. Use GetWithCallOptions to obtain the matching call option together with
+the connection.
+*/
+func WithWaitForReady() Option {
+	return func(o *lb) {
+		o.waitForReady = true
+	}
+}
+
+/*
+WithResetBatch makes a reset recreate at most n connections at a time and wait
+for delay between batches, so reconnections are spread over time instead of
+hitting the backend all at once. Between batches the pool keeps serving from
+the connections that have not been reset yet. A batch size of 0, the default,
+resets all connections in one go.
+*/
+func WithResetBatch(n uint32, delay time.Duration) Option {
+	return func(o *lb) {
+		o.resetBatch = n
+		o.resetBatchDelay = delay
+	}
+}
+
+/*
+WithOverflow lets Acquire and TryAcquire dial up to maxExtra temporary
+connections when every pooled connection is at the limit set by
+WithMaxInFlight. An overflow connection is subject to the same limit and is
+closed once it has been idle for idleTimeout, so the pool grows during bursts
+without permanently holding the extra connections.
+*/
+func WithOverflow(maxExtra uint32, idleTimeout time.Duration) Option {
+	return func(o *lb) {
+		o.maxOverflow = maxExtra
+		o.overflowIdleTimeout = idleTimeout
+	}
+}
+
+/*
+WithUsablePredicate overrides which connectivity states Get considers servable
+before triggering recovery. By default only Ready is usable, so a connection in
+any other state causes a reset once the retry interval allows it.
+*/
+func WithUsablePredicate(usable func(state connectivity.State) bool) Option {
+	return func(o *lb) {
+		if usable != nil {
+			o.usable = usable
+		}
+	}
+}
+
+/*
+WithUsableStates is a shorthand for WithUsablePredicate that treats exactly the
+given states as usable, for example Ready and Idle to let gRPC connect lazily
+from Idle instead of resetting the connection.
+*/
+func WithUsableStates(states ...connectivity.State) Option {
+	return WithUsablePredicate(func(state connectivity.State) bool {
+		for _, s := range states {
+			if s == state {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+/*
+WithResizeGrace keeps connections removed by a Resize shrink open for d, so a
+later grow can reuse them instead of dialing new ones. Parked connections are
+closed once d lapses. By default removed connections are closed immediately.
+*/
+func WithResizeGrace(d time.Duration) Option {
+	return func(o *lb) {
+		o.resizeGrace = d
+	}
+}
+
+/*
+WithMaxSize changes the largest pool size accepted by New and Resize from
+DefaultMaxSize to n.
+*/
+func WithMaxSize(n uint32) Option {
+	return func(o *lb) {
+		o.maxSize = n
+	}
+}
+
+/*
+WithLatencyAffinity makes the pool measure the round trip time of each
+connection with a health check once per probeInterval and bias selection toward
+the connections closest to the client, such as those in the local zone. Every
+tenth selection ignores the bias, so far connections stay exercised and ready
+for failover. The health check does not need to be implemented by the server;
+any answer measures the round trip.
+*/
+func WithLatencyAffinity(probeInterval time.Duration) Option {
+	return func(o *lb) {
+		o.latencyAffinity = probeInterval > 0
+		o.probeInterval = probeInterval
+	}
+}
+
+/*
+WithValidator adds an application level check on top of the connectivity state:
+a connection is only servable if validator returns true for it. An invalid
+connection triggers recovery exactly like an unhealthy one. The validator runs
+while the pool's mutex is held, so it should be cheap, for example consulting a
+cached health result rather than issuing an RPC.
+*/
+func WithValidator(validator func(conn *grpc.ClientConn) bool) Option {
+	return func(o *lb) {
+		o.validator = validator
+	}
+}
+
+/*
+WithAutoReset controls whether Get closes and redials connections when it finds
+them unhealthy. With false the pool relies on gRPC's own reconnection backoff:
+selection skips connections that are not servable, falling back to any
+connection if none is, and never resets them. ForceReset and RecycleConn keep
+working. Automatic reset is enabled by default.
+*/
+func WithAutoReset(enabled bool) Option {
+	return func(o *lb) {
+		o.autoReset = enabled
+	}
+}
+
+/*
+WithOnReset registers a callback that runs after connections have been
+recreated successfully, by an automatic reset, ForceReset or RecycleConn, with
+the indices of the recreated slots. It can for example re-establish streams
+that were dropped with the replaced connections. The callback runs after the
+pool's mutex has been released, so it may call back into the load balancer.
+*/
+func WithOnReset(onReset func(indices []int)) Option {
+	return func(o *lb) {
+		o.onReset = onReset
+	}
+}
+
+/*
+WithDrainTimeout bounds how long connections removed by Resize or Swap are
+drained before they are closed: requests still in flight when d lapses fail
+with the closed connection. By default removed connections are drained for as
+long as it takes.
+*/
+func WithDrainTimeout(d time.Duration) Option {
+	return func(o *lb) {
+		o.drainTimeout = d
+	}
+}
@@ -0,0 +1,142 @@
+package grpclb
+
+import (
+	"math"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// orcaTrailer is the trailer key under which backends send their ORCA load
+// report, a serialized xds.data.orca.v3.OrcaLoadReport.
+const orcaTrailer = "endpoint-load-metrics-bin"
+
+// orcaHalfLife is the age at which a load report counts half, so that a
+// backend that stopped reporting drifts back to neutral.
+const orcaHalfLife = 10 * time.Second
+
+// Field numbers of the OrcaLoadReport message.
+const (
+	orcaCPUUtilization         protowire.Number = 1
+	orcaApplicationUtilization protowire.Number = 9
+)
+
+/*
+WithORCA makes selection prefer the connections whose backends report the
+lowest utilization in ORCA load reports, passed to ReportTrailer. The
+application utilization of a report is used if set, the CPU utilization
+otherwise. Reports fade with a half-life of ten seconds, so a backend that
+stops reporting is treated as idle again over time. Among equally utilized
+connections, such as before the first reports, the tie breaker decides. The
+preference takes the place of the strategy; connections without reports yet
+count as idle.
+*/
+func WithORCA() Option {
+	return func(o *lb) {
+		o.orca = true
+	}
+}
+
+/*
+ReportTrailer records the outcome of a request made on conn like Report and,
+with WithORCA, the ORCA load report the backend sent in the trailer, as
+returned by grpc.Trailer. Trailers without a report or with a malformed one
+only record the outcome.
+*/
+func (o *lb) ReportTrailer(conn *grpc.ClientConn, trailer metadata.MD, err error) {
+	o.Report(conn, err)
+	if !o.orca {
+		return
+	}
+
+	values := trailer.Get(orcaTrailer)
+	if len(values) == 0 {
+		return
+	}
+
+	utilization, ok := parseUtilization([]byte(values[len(values)-1]))
+	if !ok {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if s, ok := o.bySlot[conn]; ok {
+		s.utilization, s.reportedAt = utilization, time.Now()
+	}
+}
+
+/*
+parseUtilization extracts the utilization from a serialized OrcaLoadReport:
+the application utilization if it is set, the CPU utilization otherwise. The
+memory utilization is ignored. It returns false if the report is malformed.
+*/
+func parseUtilization(report []byte) (float64, bool) {
+	cpu, application := 0.0, 0.0
+	for len(report) > 0 {
+		num, typ, n := protowire.ConsumeTag(report)
+		if n < 0 {
+			return 0, false
+		}
+		report = report[n:]
+
+		if typ == protowire.Fixed64Type && (num == orcaCPUUtilization || num == orcaApplicationUtilization) {
+			bits, n := protowire.ConsumeFixed64(report)
+			if n < 0 {
+				return 0, false
+			}
+			report = report[n:]
+
+			if num == orcaCPUUtilization {
+				cpu = math.Float64frombits(bits)
+			} else {
+				application = math.Float64frombits(bits)
+			}
+
+			continue
+		}
+
+		if n = protowire.ConsumeFieldValue(num, typ, report); n < 0 {
+			return 0, false
+		}
+		report = report[n:]
+	}
+
+	if application > 0 {
+		return application, true
+	}
+
+	return cpu, true
+}
+
+/*
+utilizationOf returns the slot's last reported utilization, decayed by its
+age. The caller must hold the mutex.
+*/
+func utilizationOf(s *slot, now time.Time) float64 {
+	if s.reportedAt.IsZero() {
+		return 0
+	}
+
+	return s.utilization * math.Exp2(-float64(now.Sub(s.reportedAt))/float64(orcaHalfLife))
+}
+
+/*
+pickLeastUtilized picks the accepted slot with the lowest decayed utilization,
+preferring servable slots. The caller must hold the mutex.
+*/
+func (o *lb) pickLeastUtilized(accept func(s *slot) bool) *slot {
+	now := time.Now()
+	utilization := func(s *slot) float64 {
+		return utilizationOf(s, now)
+	}
+
+	if s := o.pickMin(both(accept, o.servable), utilization); s != nil {
+		return s
+	}
+
+	return o.pickMin(accept, utilization)
+}
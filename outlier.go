@@ -0,0 +1,103 @@
+package grpclb
+
+import "time"
+
+/*
+OutlierConfig configures outlier detection. A connection is ejected from
+rotation when ConsecutiveFailures reported failures happen in a row, or when
+its success rate drops below MinSuccessRate after at least MinRequests reported
+requests. Zero values disable the respective check.
+
+An ejected connection is skipped by selection for BaseEjectionTime multiplied
+by the number of times it has been ejected, capped at MaxEjectionTime, and then
+put back into rotation, where the next requests probe whether it recovered. At
+most MaxEjectionPercent of the connections, 10 by default, are ejected at the
+same time, but at least one may be ejected as long as one is left to serve.
+*/
+type OutlierConfig struct {
+	ConsecutiveFailures uint32
+	MinSuccessRate      float64
+	MinRequests         uint64
+	BaseEjectionTime    time.Duration
+	MaxEjectionTime     time.Duration
+	MaxEjectionPercent  uint32
+}
+
+/*
+WithOutlierDetection enables outlier detection driven by the outcomes passed
+to Report.
+*/
+func WithOutlierDetection(cfg OutlierConfig) Option {
+	return func(o *lb) {
+		if cfg.MaxEjectionPercent == 0 {
+			cfg.MaxEjectionPercent = 10
+		}
+
+		o.outlier = &cfg
+	}
+}
+
+/*
+ejected reports whether the slot is ejected at the given time. The caller must
+hold the mutex.
+*/
+func (o *lb) ejected(s *slot, now time.Time) bool {
+	return o.outlier != nil && now.Before(s.ejectedUntil)
+}
+
+/*
+detectOutlier ejects the slot if its reported outcomes cross one of the
+configured thresholds. The caller must hold the mutex.
+*/
+func (o *lb) detectOutlier(s *slot) {
+	cfg := o.outlier
+	now := time.Now()
+	if o.ejected(s, now) {
+		return
+	}
+
+	reason := ""
+	if cfg.ConsecutiveFailures > 0 && s.consecutiveFailures >= cfg.ConsecutiveFailures {
+		reason = "consecutive_failures"
+	} else if cfg.MinSuccessRate > 0 && s.successes+s.failures >= cfg.MinRequests && s.success < cfg.MinSuccessRate {
+		reason = "success_rate"
+	}
+
+	if reason == "" || !o.canEject(now) {
+		return
+	}
+
+	s.ejections++
+	ejection := cfg.BaseEjectionTime * time.Duration(s.ejections)
+	if cfg.MaxEjectionTime > 0 && ejection > cfg.MaxEjectionTime {
+		ejection = cfg.MaxEjectionTime
+	}
+
+	s.ejectedUntil = now.Add(ejection)
+	s.consecutiveFailures = 0
+	s.success = 1
+	o.log("Ejected connection " + itoa(s.index) + " for " + ejection.String())
+	o.notifyRemove(s, reason)
+	o.scheduleReadd(s)
+}
+
+/*
+canEject reports whether one more connection may be ejected without exceeding
+the maximum ejection percentage or leaving no connection to serve. The caller
+must hold the mutex.
+*/
+func (o *lb) canEject(now time.Time) bool {
+	ejected := uint32(0)
+	for _, s := range o.slots {
+		if o.ejected(s, now) {
+			ejected++
+		}
+	}
+
+	limit := o.size * o.outlier.MaxEjectionPercent / 100
+	if limit == 0 {
+		limit = 1
+	}
+
+	return ejected < limit && ejected+1 < o.size
+}
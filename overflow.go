@@ -0,0 +1,70 @@
+package grpclb
+
+import "time"
+
+/*
+overflowSlot returns an overflow connection below the in-flight limit, dialing
+a new one if the limit set by WithOverflow has not been reached yet. It returns
+nil if overflow is disabled, exhausted or the factory fails. The caller must
+hold the mutex.
+*/
+func (o *lb) overflowSlot() *slot {
+	for _, s := range o.overflow {
+		if o.belowLimit(s) {
+			return s
+		}
+	}
+
+	if uint32(len(o.overflow)) >= o.maxOverflow {
+		return nil
+	}
+
+	conn, err := o.dial(o.size + uint32(len(o.overflow)))
+	if err != nil {
+		o.log("Failed to create overflow connection: " + err.Error())
+		return nil
+	}
+
+	s := &slot{conn: conn, index: o.size + uint32(len(o.overflow)), overflow: true}
+	o.overflow = append(o.overflow, s)
+	return s
+}
+
+/*
+scheduleRetire arms the idle timer of an overflow slot that just became idle.
+The caller must hold the mutex.
+*/
+func (o *lb) scheduleRetire(s *slot) {
+	s.idleSince = time.Now().UTC()
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+	}
+
+	s.idleTimer = time.AfterFunc(o.overflowIdleTimeout, func() {
+		o.retire(s)
+	})
+}
+
+/*
+retire closes an overflow connection and removes it from the pool if it has
+stayed idle for the whole idle timeout.
+*/
+func (o *lb) retire(s *slot) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if s.inFlight > 0 || time.Now().UTC().Sub(s.idleSince) < o.overflowIdleTimeout {
+		return
+	}
+
+	for i, other := range o.overflow {
+		if other == s {
+			o.overflow = append(o.overflow[:i], o.overflow[i+1:]...)
+			if err := o.closeConn(s.conn); err != nil {
+				o.log("Failed to close overflow connection: " + err.Error())
+			}
+
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package grpclb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+/*
+DrainConn takes the connection at the given index out of rotation for
+maintenance, waits until its requests acquired through Acquire have been
+released, then closes it and dials a replacement, which rejoins the rotation.
+The other connections are not affected. While the connection drains, it is
+only selected if no other connection is available. If ctx expires first, the
+connection is put back into rotation unchanged and ctx's error is returned.
+*/
+func (o *lb) DrainConn(ctx context.Context, index uint32) error {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return ErrClosed
+	}
+
+	if index >= o.size {
+		return fmt.Errorf("index %d out of range for pool of size %d", index, o.size)
+	}
+
+	s := o.slots[index]
+	if s.paused {
+		return fmt.Errorf("connection %d is already draining", index)
+	}
+
+	o.pause(s, true)
+	defer o.pause(s, false)
+
+	for s.inFlight > 0 {
+		released := o.released
+		o.unlock()
+
+		select {
+		case <-ctx.Done():
+			o.mutex.Lock()
+			return ctx.Err()
+		case <-released:
+		}
+
+		o.mutex.Lock()
+		if o.closed {
+			return ErrClosed
+		}
+
+		if index >= o.size || o.slots[index] != s {
+			return errors.New("connection was removed from the pool while draining")
+		}
+	}
+
+	if err := o.resetConn(s); err != nil {
+		return err
+	}
+
+	o.notifyReset([]int{int(index)})
+	o.notifyReleased()
+	return nil
+}
+
+/*
+pause takes the slot out of rotation or puts it back. The caller must hold the
+mutex.
+*/
+func (o *lb) pause(s *slot, paused bool) {
+	if s.paused == paused {
+		return
+	}
+
+	s.paused = paused
+	if paused {
+		o.paused++
+	} else {
+		o.paused--
+	}
+
+	o.fastStale = true
+}
+
+/*
+active reports whether the slot is in rotation. The caller must hold the
+mutex.
+*/
+func (o *lb) active(s *slot) bool {
+	return !s.paused
+}
@@ -0,0 +1,79 @@
+package grpclb
+
+import (
+	"fmt"
+	"time"
+)
+
+// pinLogInterval is how often a pin that keeps affecting selection is logged
+// again.
+const pinLogInterval = time.Minute
+
+/*
+Pin makes selection return the connection at index whenever it is servable,
+through every selection method, for example to reproduce an issue with one
+particular backend via the normal code path. While the pinned connection is
+not servable, or not accepted by the selection method such as GetExcluding,
+selection works as usual. The pin lasts until Unpin, or until Resize removes
+the pinned connection. It is logged when set and when lifted, and once a
+minute while it keeps deciding selections, with their count. It follows the
+connection if a shrink moves it to another index. It returns an error if index
+is out of range.
+*/
+func (o *lb) Pin(index uint32) error {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return ErrClosed
+	}
+
+	if index >= o.size {
+		return fmt.Errorf("index %d out of range for pool of size %d", index, o.size)
+	}
+
+	o.pin, o.pinned = index, true
+	o.pinLogged, o.pinPicks = time.Now(), 0
+	o.fastStale = true
+	o.log("Pinned selection to connection " + itoa(index) + "; call Unpin to restore load balancing")
+	return nil
+}
+
+/*
+Unpin lifts the pin set with Pin. It has no effect if no pin is set.
+*/
+func (o *lb) Unpin() {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if !o.pinned {
+		return
+	}
+
+	o.pinned = false
+	o.fastStale = true
+	o.log("Unpinned selection from connection " + itoa(o.pin))
+}
+
+/*
+pinnedSlot returns the pinned slot if it is servable and accepted, nil
+otherwise, counting the selection it decides. The caller must hold the mutex.
+*/
+func (o *lb) pinnedSlot(accept func(s *slot) bool) *slot {
+	if !o.pinned || o.pin >= o.size {
+		return nil
+	}
+
+	s := o.slots[o.pin]
+	if !o.servable(s) || (accept != nil && !accept(s)) {
+		return nil
+	}
+
+	o.pinPicks++
+	if time.Since(o.pinLogged) >= pinLogInterval {
+		o.log("Selection is pinned to connection " + itoa(o.pin) + ", " + itoa(o.pinPicks) + " selections since the last report; call Unpin to restore load balancing")
+		o.pinLogged, o.pinPicks = time.Now(), 0
+	}
+
+	return s
+}
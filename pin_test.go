@@ -0,0 +1,116 @@
+package grpclb
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestPinIsLoggedWhileActive(t *testing.T) {
+	ts := startServer(t)
+	var mu sync.Mutex
+	var reports []string
+	logger := func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(msg, "Selection is pinned") {
+			reports = append(reports, msg)
+		}
+	}
+
+	l, err := New(2, 1, ts.factory(), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+	connectAll(t, o)
+
+	if err := o.Pin(1); err != nil {
+		t.Fatal(err)
+	}
+
+	logged := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), reports...)
+	}
+
+	for i := 0; i < 3; i++ {
+		o.Get()
+	}
+	if got := logged(); len(got) != 0 {
+		t.Fatalf("the pin was reported within a minute of Pin: %q", got)
+	}
+
+	o.mutex.Lock()
+	o.pinLogged = o.pinLogged.Add(-pinLogInterval)
+	o.unlock()
+
+	for i := 0; i < 3; i++ {
+		o.Get()
+	}
+	got := logged()
+	if len(got) != 1 || !strings.Contains(got[0], "connection 1, 4 selections") {
+		t.Fatalf("reports = %q, want one for the 4 selections the pin decided", got)
+	}
+
+	o.Unpin()
+	o.mutex.Lock()
+	o.pinLogged = time.Time{}
+	o.unlock()
+	o.Get()
+	if got := logged(); len(got) != 1 {
+		t.Errorf("the pin was reported after Unpin: %q", got[1:])
+	}
+}
+
+func TestPinDoesNotBypassGetHealthy(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 2, WithUsableStates(connectivity.Ready, connectivity.Idle))
+
+	ready := o.slots[1].conn
+	ready.Connect()
+	waitState(t, ready, connectivity.Ready)
+	if err := o.Pin(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := o.slots[0].conn.GetState(); state != connectivity.Idle {
+		t.Fatalf("the pinned connection is %v, want Idle", state)
+	}
+
+	conn, err := o.GetHealthy()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conn != ready {
+		t.Fatal("GetHealthy returned the pinned connection although it is not Ready")
+	}
+}
+
+func TestPinDoesNotBypassGetExcluding(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 2)
+	connectAll(t, o)
+	if err := o.Pin(0); err != nil {
+		t.Fatal(err)
+	}
+
+	pinned := o.slots[0].conn
+	if conn := o.GetExcluding(pinned); conn != o.slots[1].conn {
+		t.Fatal("GetExcluding returned the excluded pinned connection")
+	}
+
+	if err := o.Resize(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if conn := o.GetExcluding(pinned); conn != pinned {
+		t.Fatal("GetExcluding did not fall back to the excluded connection when it is the only one")
+	}
+}
@@ -0,0 +1,191 @@
+package grpclb
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+/*
+PooledConn is a connection acquired from the load balancer together with its
+in-flight accounting. The connection stays counted as in flight until Done is
+called, so the usual pattern is:
+
+	pc, err := lb.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer pc.Done()
+	client := pb.NewFooClient(pc.Conn())
+*/
+type PooledConn struct {
+	lb    *lb
+	slot  *slot
+	conn  *grpc.ClientConn
+	bytes int64
+	once  sync.Once
+}
+
+/*
+Conn returns the underlying gRPC connection. It returns nil if the PooledConn
+is nil.
+*/
+func (p *PooledConn) Conn() *grpc.ClientConn {
+	if p == nil {
+		return nil
+	}
+
+	return p.conn
+}
+
+/*
+Done releases the connection, decrementing its in-flight counters. It is safe
+to call Done more than once and on a nil PooledConn; only the first call has
+an effect.
+*/
+func (p *PooledConn) Done() {
+	if p == nil {
+		return
+	}
+
+	p.once.Do(func() {
+		p.lb.release(p.slot, p.conn, p.bytes)
+	})
+}
+
+/*
+Acquire returns the next connection that is below the in-flight limit set by
+WithMaxInFlight, wrapped in a PooledConn. If every connection is at its limit,
+Acquire blocks until one is released or ctx is done, in which case the context
+error is returned. Without a limit Acquire never blocks. If the connections
+need a reset and the reset fails, the reset error is returned.
+*/
+func (o *lb) Acquire(ctx context.Context) (*PooledConn, error) {
+	return o.AcquireBytes(ctx, 0)
+}
+
+/*
+AcquireBytes is like Acquire for a request carrying the given number of payload
+bytes, which count against the connection's bytes in flight until Done is
+called. The LeastBytesInFlight strategy selects by these counters.
+*/
+func (o *lb) AcquireBytes(ctx context.Context, bytes int64) (*PooledConn, error) {
+	for {
+		o.mutex.Lock()
+		pc, err := o.acquire(bytes)
+		released := o.released
+		o.unlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if pc != nil {
+			return pc, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+/*
+TryAcquire is the non-blocking variant of Acquire. It returns false if every
+connection is at its in-flight limit or no connection could be selected.
+*/
+func (o *lb) TryAcquire() (*PooledConn, bool) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	pc, err := o.acquire(0)
+	if err != nil || pc == nil {
+		return nil, false
+	}
+
+	return pc, true
+}
+
+/*
+Checkout selects a connection with in-flight accounting, like TryAcquire, and
+returns it together with the function that releases it, for the common
+pattern conn, release := lb.Checkout(); defer release(). Load aware strategies
+such as WeightedLeastConnections see the request as in flight until release
+is called. If no connection can be acquired, conn is nil. release is never nil
+and only its first call has an effect. It is the context-free counterpart of
+Acquire, which returns a PooledConn instead.
+*/
+func (o *lb) Checkout() (*grpc.ClientConn, func()) {
+	pc, ok := o.TryAcquire()
+	if !ok {
+		return nil, func() {}
+	}
+
+	return pc.Conn(), pc.Done
+}
+
+/*
+acquire selects a slot below the in-flight limit and counts the new request
+against it. If every slot is at its limit, an overflow connection is used when
+WithOverflow allows it. It returns nil if no capacity is left. The caller must
+hold the mutex.
+*/
+func (o *lb) acquire(bytes int64) (*PooledConn, error) {
+	s, err := o.next(o.belowLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil {
+		s = o.overflowSlot()
+		if s == nil {
+			return nil, nil
+		}
+	}
+
+	s.inFlight++
+	s.bytesInFlight += bytes
+	return &PooledConn{lb: o, slot: s, conn: s.conn, bytes: bytes}, nil
+}
+
+/*
+belowLimit reports whether the slot can take another in-flight request.
+*/
+func (o *lb) belowLimit(s *slot) bool {
+	return o.maxInFlight == 0 || s.inFlight < o.maxInFlight
+}
+
+/*
+release decrements the in-flight counters of the given slot and wakes up any
+Acquire waiting for capacity. If the slot's connection has been replaced since
+it was acquired, the counters already belong to the new connection and are
+left untouched, unless the old connection is still draining after
+WithGoAwayRefresh replaced it; its retired slot is released then.
+*/
+func (o *lb) release(s *slot, conn *grpc.ClientConn, bytes int64) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s = o.holder(s, conn)
+	if s.conn == conn && s.inFlight > 0 {
+		s.inFlight--
+		s.bytesInFlight -= bytes
+		if s.overflow && s.inFlight == 0 {
+			o.scheduleRetire(s)
+		}
+
+		o.notifyReleased()
+	}
+}
+
+/*
+notifyReleased wakes up every Acquire waiting for capacity. The caller must
+hold the mutex.
+*/
+func (o *lb) notifyReleased() {
+	close(o.released)
+	o.released = make(chan struct{})
+}
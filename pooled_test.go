@@ -0,0 +1,39 @@
+package grpclb
+
+import "testing"
+
+func TestCheckoutCountsInFlight(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 1, WithStrategy(WeightedLeastConnections))
+
+	conn, release := o.Checkout()
+	if conn == nil {
+		t.Fatal("Checkout returned no connection")
+	}
+
+	inFlight := func() int64 {
+		o.mutex.Lock()
+		defer o.unlock()
+		return int64(o.slots[0].inFlight)
+	}
+
+	if n := inFlight(); n != 1 {
+		t.Fatalf("%d requests in flight after Checkout, want 1", n)
+	}
+
+	release()
+	release()
+	if n := inFlight(); n != 0 {
+		t.Fatalf("%d requests in flight after release, want 0", n)
+	}
+
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, release = o.Checkout()
+	if conn != nil || release == nil {
+		t.Fatal("Checkout on a closed pool must return no connection and a release function")
+	}
+	release()
+}
@@ -0,0 +1,48 @@
+package grpclb
+
+import "time"
+
+// preferRecoveredEvery sends every n-th selection to a recently recovered
+// connection while there is one, leaving the rest to regular selection.
+const preferRecoveredEvery = 2
+
+/*
+WithPreferRecovered biases selection towards connections that became usable
+within the last boost, so that capacity coming back after an outage picks up
+load quickly instead of waiting for round-robin to spread it. While such
+connections exist, every other selection goes to one of them. The time a
+connection became usable is recorded by the state watcher.
+*/
+func WithPreferRecovered(boost time.Duration) Option {
+	return func(o *lb) {
+		o.preferRecovered = boost
+	}
+}
+
+/*
+pickRecovered picks an accepted slot that recovered within the boost window,
+on every preferRecoveredEvery-th selection. It returns nil otherwise. The
+caller must hold the mutex.
+*/
+func (o *lb) pickRecovered(accept func(s *slot) bool) *slot {
+	if o.preferRecovered <= 0 || o.useCount%preferRecoveredEvery != 0 {
+		return nil
+	}
+
+	now := time.Now()
+	return o.scan(both(accept, func(s *slot) bool {
+		return !s.recoveredAt.IsZero() && now.Sub(s.recoveredAt) < o.preferRecovered
+	}))
+}
+
+/*
+recordRecovery records the time the slot's connection became usable. The
+caller must hold the mutex.
+*/
+func (o *lb) recordRecovery(s *slot, usable bool) {
+	if usable && !s.usable {
+		s.recoveredAt = time.Now()
+	}
+
+	s.usable = usable
+}
@@ -0,0 +1,111 @@
+package grpclb
+
+import (
+	"errors"
+	"time"
+)
+
+/*
+WithPriorities assigns a priority tier to every connection, one entry per
+index. Selection uses the connections of the lowest numbered tier that has a
+servable connection exclusively, falling back to higher tiers only when all of
+its connections are down. New returns an error if the number of priorities does
+not match the size.
+*/
+func WithPriorities(priorities []int) Option {
+	return func(o *lb) {
+		o.priorities = append([]int(nil), priorities...)
+	}
+}
+
+/*
+SetPriorities replaces the priority tiers of the connections at runtime, one
+entry per index, with the same semantics as WithPriorities. It returns an
+error if the number of priorities does not match the current size.
+*/
+func (o *lb) SetPriorities(priorities []int) error {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return ErrClosed
+	}
+
+	if len(priorities) != int(o.size) {
+		return errors.New("priorities must have one entry per connection")
+	}
+
+	o.priorities = append([]int(nil), priorities...)
+	o.fastStale = true
+	return nil
+}
+
+/*
+bestTier returns the lowest priority among the servable slots that are not
+ejected, or false if there is no such slot. The caller must hold the mutex.
+*/
+func (o *lb) bestTier() (int, bool) {
+	now := time.Now()
+	tier, ok := 0, false
+	for _, s := range o.slots {
+		priority := o.priorities[s.index]
+		if (!ok || priority < tier) && o.servable(s) && !o.ejected(s, now) {
+			tier, ok = priority, true
+		}
+	}
+
+	return tier, ok
+}
+
+/*
+WithPrioritySpillover lets traffic spill over from the best tier into the next
+one gradually instead of only when the best tier is down. Once the servable
+connections of the best tier carry loadThreshold requests in flight on
+average, the share of selections above that level goes to the next tier: at
+twice the threshold, half of the selections spill over. Only requests
+acquired through Acquire count as in flight. It requires WithPriorities.
+*/
+func WithPrioritySpillover(loadThreshold float64) Option {
+	return func(o *lb) {
+		o.spillover = loadThreshold
+	}
+}
+
+/*
+spill returns the tier to select from given the best tier: the next tier with a
+servable connection for the share of selections that spills over, tier
+otherwise. The caller must hold the mutex.
+*/
+func (o *lb) spill(tier int) int {
+	if o.spillover <= 0 {
+		return tier
+	}
+
+	now := time.Now()
+	next, found := 0, false
+	inFlight, n := int64(0), 0
+	for _, s := range o.slots {
+		if !o.servable(s) || o.ejected(s, now) {
+			continue
+		}
+
+		switch priority := o.priorities[s.index]; {
+		case priority == tier:
+			inFlight += s.inFlight
+			n++
+		case priority > tier && (!found || priority < next):
+			next, found = priority, true
+		}
+	}
+
+	if !found || n == 0 {
+		return tier
+	}
+
+	load := float64(inFlight) / float64(n)
+	if load <= o.spillover || o.rand.Float64() >= 1-o.spillover/load {
+		return tier
+	}
+
+	return next
+}
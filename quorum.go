@@ -0,0 +1,112 @@
+package grpclb
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+/*
+ErrNoQuorum is returned by GetQuorum when fewer servable connections with
+distinct tag values exist than requested.
+*/
+var ErrNoQuorum = errors.New("no quorum of connections with distinct tags")
+
+/*
+GetQuorum selects r servable connections whose values of the tag distinctBy,
+see WithTags, all differ, for quorum reads and writes that must reach distinct
+backends rather than merely distinct slots. Connections without the tag are
+never selected. Among the candidates the connections are picked the same way
+as by Get. The pool is recovered at most once per call, before any connection
+is picked, so the connections returned all belong to the same snapshot of the
+pool. An error wrapping ErrNoQuorum is returned if fewer than r connections
+qualify, and an error if r is not positive or the pool has no tags.
+*/
+func (o *lb) GetQuorum(r int, distinctBy string) ([]*grpc.ClientConn, error) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return nil, ErrClosed
+	}
+
+	switch {
+	case r <= 0:
+		return nil, fmt.Errorf("quorum size %d must be positive", r)
+	case o.tags == nil:
+		return nil, errors.New("GetQuorum requires WithTags")
+	}
+
+	tagged := func(s *slot) bool {
+		_, ok := o.tags[s.index][distinctBy]
+		return ok && o.servable(s)
+	}
+
+	pinned := o.pinnedSlot(both(tagged, o.unleased))
+	unpinned := func(s *slot) bool {
+		return s != pinned
+	}
+
+	// The wrappers in nextWith may run the picker more than once, so it picks
+	// without counting and rewinds the round-robin state of a discarded
+	// round; only the final picks are counted.
+	var (
+		picked []*slot
+		rewind func()
+	)
+
+	_, err := o.nextWith(both(tagged, unpinned), func(accept func(s *slot) bool) *slot {
+		o.probing = true
+		defer func() {
+			o.probing = false
+		}()
+
+		if rewind == nil {
+			offset, readyOffset := o.offset, o.readyOffset
+			rewind = func() {
+				o.offset, o.readyOffset = offset, readyOffset
+			}
+		} else {
+			rewind()
+		}
+
+		picked = picked[:0]
+		taken := make(map[string]bool, r)
+		untaken := func(s *slot) bool {
+			return !taken[o.tags[s.index][distinctBy]]
+		}
+
+		if pinned != nil {
+			picked = append(picked, pinned)
+			taken[o.tags[pinned.index][distinctBy]] = true
+		}
+
+		for len(picked) < r {
+			s := o.pick(both(accept, untaken))
+			if s == nil {
+				return nil
+			}
+
+			picked = append(picked, s)
+			taken[o.tags[s.index][distinctBy]] = true
+		}
+
+		return picked[len(picked)-1]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(picked) < r {
+		return nil, fmt.Errorf("%w: %d of %d with distinct %q", ErrNoQuorum, len(picked), r, distinctBy)
+	}
+
+	conns := make([]*grpc.ClientConn, len(picked))
+	for i, s := range picked {
+		o.count(s)
+		conns[i] = s.conn
+	}
+
+	return conns, nil
+}
@@ -0,0 +1,92 @@
+package grpclb
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestGetQuorum(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 3, WithTags([]map[string]string{
+		{"host": "a"}, {"host": "b"}, {"host": "a"},
+	}))
+	connectAll(t, o)
+
+	conns, err := o.GetQuorum(2, "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := make(map[string]bool)
+	for _, conn := range conns {
+		hosts[o.tags[o.bySlot[conn].index]["host"]] = true
+	}
+
+	if len(conns) != 2 || len(hosts) != 2 {
+		t.Errorf("GetQuorum returned %d connections on %d hosts, want 2 on 2", len(conns), len(hosts))
+	}
+
+	if _, err := o.GetQuorum(3, "host"); !errors.Is(err, ErrNoQuorum) {
+		t.Errorf("GetQuorum(3): got error %v, want ErrNoQuorum", err)
+	}
+}
+
+func TestGetQuorumNeverReturnsClosedConns(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 3, WithIdleGrace(0), WithTags([]map[string]string{
+		{"host": "a"}, {"host": "b"}, {"host": "c"},
+	}))
+
+	for _, i := range []int{0, 2} {
+		o.slots[i].conn.Connect()
+		waitState(t, o.slots[i].conn, connectivity.Ready)
+	}
+
+	// The slot at index 1 is unservable after having been handed out, so a
+	// selection starting there resets the pool.
+	atomic.StoreUint64(&o.slots[1].uses, 1)
+	o.allowReset()
+
+	conns, err := o.GetQuorum(2, "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, conn := range conns {
+		if conn.GetState() == connectivity.Shutdown {
+			t.Error("GetQuorum returned a connection closed by a reset")
+		}
+	}
+}
+
+func TestGetQuorumCountsOnlyFinalPicks(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 3, WithSlowStart(time.Hour), WithTags([]map[string]string{
+		{"host": "a"}, {"host": "b"}, {"host": "c"},
+	}))
+	connectAll(t, o)
+
+	// Fresh connections are mostly left out of the slow start round, so the
+	// picker usually runs a second time without it.
+	for i := 0; i < 20; i++ {
+		o.mutex.Lock()
+		for _, s := range o.slots {
+			s.readySince = time.Now()
+		}
+		o.unlock()
+
+		if _, err := o.GetQuorum(3, "host"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, s := range o.slots {
+		if uses := atomic.LoadUint64(&s.uses); uses != 20 {
+			t.Errorf("connection %d counted %d uses for 20 quorums, want 20", s.index, uses)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package grpclb
+
+import (
+	"math/rand"
+	"sync"
+)
+
+/*
+WithRandSource makes the load balancer draw all its random decisions, such as
+weighted random sampling and the staggered dial delays, from src instead of a
+source seeded with the current time, so tests can assert exact selection
+sequences with a fixed seed. Clones made with Clone share src; access to it is
+serialized, so src need not be safe for concurrent use.
+*/
+func WithRandSource(src rand.Source) Option {
+	locked := &lockedSource{src: src}
+	return func(o *lb) {
+		o.rand = rand.New(locked)
+	}
+}
+
+/*
+lockedSource serializes access to a rand.Source shared between load balancers.
+*/
+type lockedSource struct {
+	mutex sync.Mutex
+	src   rand.Source
+}
+
+/*
+Int63 implements rand.Source.
+*/
+func (s *lockedSource) Int63() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.src.Int63()
+}
+
+/*
+Seed implements rand.Source.
+*/
+func (s *lockedSource) Seed(seed int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.src.Seed(seed)
+}
@@ -0,0 +1,61 @@
+package grpclb
+
+import (
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+RankedConn is an entry of RankedConns: a connection, identified by its index
+and target, with its state and health score.
+*/
+type RankedConn struct {
+	Index  uint32
+	Target string
+	State  connectivity.State
+	Score  float64
+}
+
+/*
+RankedConns returns a snapshot of the connections ordered best first, for
+example for a dashboard listing the backends. The score ranks Ready connections
+above connecting ones, which rank above failed, shut down or ejected ones.
+Within a rank, a higher success rate and fewer requests in flight score
+higher: the score is the rank plus the success rate divided by one more than
+the number of requests in flight. Connections with equal scores are ordered by
+index. The snapshot is taken under the mutex.
+*/
+func (o *lb) RankedConns() []RankedConn {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	now := time.Now()
+	ranked := make([]RankedConn, 0, len(o.slots))
+	for _, s := range o.slots {
+		state := s.conn.GetState()
+
+		rank := 0.0
+		switch {
+		case o.ejected(s, now):
+		case state == connectivity.Ready:
+			rank = 2
+		case state == connectivity.Idle || state == connectivity.Connecting:
+			rank = 1
+		}
+
+		ranked = append(ranked, RankedConn{
+			Index:  s.index,
+			Target: s.conn.Target(),
+			State:  state,
+			Score:  rank + s.success/float64(1+s.inFlight),
+		})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	return ranked
+}
@@ -0,0 +1,76 @@
+package grpclb
+
+import (
+	"errors"
+
+	"google.golang.org/grpc"
+)
+
+/*
+ReadWriteLB manages two load balancers, one for reads and one for writes, for
+backends that serve them from different endpoints. Giving the pools names with
+WithName keeps their log output apart.
+*/
+type ReadWriteLB struct {
+	read  LB
+	write LB
+}
+
+/*
+NewReadWrite combines a read and a write load balancer into one manager. Both
+must be non-nil and distinct.
+*/
+func NewReadWrite(read, write LB) (*ReadWriteLB, error) {
+	switch {
+	case read == nil:
+		return nil, errors.New("read load balancer can't be nil")
+	case write == nil:
+		return nil, errors.New("write load balancer can't be nil")
+	case read == write:
+		return nil, errors.New("read and write load balancers must be distinct")
+	}
+
+	return &ReadWriteLB{read: read, write: write}, nil
+}
+
+/*
+GetRead returns the next connection of the read pool.
+*/
+func (rw *ReadWriteLB) GetRead() *grpc.ClientConn {
+	return rw.read.Get()
+}
+
+/*
+GetWrite returns the next connection of the write pool.
+*/
+func (rw *ReadWriteLB) GetWrite() *grpc.ClientConn {
+	return rw.write.Get()
+}
+
+/*
+Read returns the read pool, for the methods not wrapped by ReadWriteLB.
+*/
+func (rw *ReadWriteLB) Read() LB {
+	return rw.read
+}
+
+/*
+Write returns the write pool, for the methods not wrapped by ReadWriteLB.
+*/
+func (rw *ReadWriteLB) Write() LB {
+	return rw.write
+}
+
+/*
+Close closes both pools. Both are closed even if the first one fails, and the
+first error is returned.
+*/
+func (rw *ReadWriteLB) Close() error {
+	readErr := rw.read.Close()
+	writeErr := rw.write.Close()
+	if readErr != nil {
+		return readErr
+	}
+
+	return writeErr
+}
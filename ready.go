@@ -0,0 +1,157 @@
+package grpclb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+WaitAllReady blocks until every connection in the pool is Ready. Idle
+connections are asked to connect. If ctx is done first, the returned error
+lists the indices of the connections that did not become ready and wraps the
+context error.
+*/
+func (o *lb) WaitAllReady(ctx context.Context) error {
+	o.mutex.Lock()
+	if o.closed {
+		o.unlock()
+		return ErrClosed
+	}
+
+	conns := make([]*grpc.ClientConn, len(o.slots))
+	for i, s := range o.slots {
+		conns[i] = s.conn
+	}
+	o.unlock()
+
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		failed []uint32
+	)
+
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(index uint32, conn *grpc.ClientConn) {
+			defer wg.Done()
+
+			if !waitReady(ctx, conn) {
+				mutex.Lock()
+				failed = append(failed, index)
+				mutex.Unlock()
+			}
+		}(uint32(i), conn)
+	}
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Slice(failed, func(i, j int) bool { return failed[i] < failed[j] })
+		return fmt.Errorf("connections %v did not become ready: %w", failed, ctx.Err())
+	}
+
+	return nil
+}
+
+/*
+waitReady waits for conn to become Ready and reports whether it did before ctx
+was done.
+*/
+func waitReady(ctx context.Context, conn *grpc.ClientConn) bool {
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return true
+		case connectivity.Idle:
+			conn.Connect()
+		}
+
+		if !conn.WaitForStateChange(ctx, state) {
+			return false
+		}
+	}
+}
+
+/*
+ReadyChan returns a channel that is closed once a connection of the pool is
+Ready for the first time, to select on alongside other startup signals. It
+stays closed afterwards, even if the connections fail later. Readiness is
+tracked by the state watcher from the first call on, which also asks idle
+connections to connect; connections that are Ready already close the channel
+right away.
+*/
+func (o *lb) ReadyChan() <-chan struct{} {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	select {
+	case <-o.readyCh:
+		return o.readyCh
+	default:
+	}
+
+	if !o.trackReady && !o.closed {
+		o.trackReady = true
+		for _, s := range o.slots {
+			if s.stopWatch == nil {
+				o.startWatch(s)
+			}
+
+			if s.conn.GetState() == connectivity.Idle {
+				s.conn.Connect()
+			}
+		}
+	}
+
+	return o.readyCh
+}
+
+/*
+markReady closes the ready channel once a connection became Ready and stops
+tracking readiness. The caller must hold the mutex.
+*/
+func (o *lb) markReady() {
+	o.trackReady = false
+	close(o.readyCh)
+}
+
+/*
+WaitUntilUnhealthy blocks until the connection at index leaves the Ready state,
+for example to verify failover in tests after injecting a failure. It returns
+right away if the connection is not Ready, and ctx's error if ctx is done
+first. A connection replaced in the meantime counts as having left the Ready
+state.
+*/
+func (o *lb) WaitUntilUnhealthy(ctx context.Context, index uint32) error {
+	o.mutex.Lock()
+	if o.closed {
+		o.unlock()
+		return ErrClosed
+	}
+
+	if index >= o.size {
+		size := o.size
+		o.unlock()
+		return fmt.Errorf("index %d out of range for pool of size %d", index, size)
+	}
+
+	conn := o.slots[index].conn
+	o.unlock()
+
+	for {
+		state := conn.GetState()
+		if state != connectivity.Ready {
+			return nil
+		}
+
+		if !conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package grpclb
+
+/*
+WithReadySet makes selection round-robin over a set of ready connections
+maintained by a state watcher, instead of probing the state of connections on
+every call. Selecting from the set takes constant time while most connections
+are usable and only a few flap. When the set is empty the pool triggers
+recovery and falls back to plain round-robin.
+*/
+func WithReadySet() Option {
+	return func(o *lb) {
+		o.readySet = true
+	}
+}
+
+/*
+addReady adds the slot to the ready set. The caller must hold the mutex.
+*/
+func (o *lb) addReady(s *slot) {
+	if s.ready {
+		return
+	}
+
+	s.ready = true
+	s.readyPos = len(o.ready)
+	o.ready = append(o.ready, s)
+}
+
+/*
+removeReady removes the slot from the ready set by moving the last member into
+its position. The caller must hold the mutex.
+*/
+func (o *lb) removeReady(s *slot) {
+	if !s.ready {
+		return
+	}
+
+	last := o.ready[len(o.ready)-1]
+	o.ready[s.readyPos] = last
+	last.readyPos = s.readyPos
+	o.ready[len(o.ready)-1] = nil
+	o.ready = o.ready[:len(o.ready)-1]
+	s.ready = false
+}
+
+/*
+scanReady walks the ready set in round-robin order and returns the first slot
+accepted by accept, or nil if there is no ready set or no ready slot is
+accepted. The caller must hold the mutex.
+*/
+func (o *lb) scanReady(accept func(s *slot) bool) *slot {
+	for i := 0; i < len(o.ready); i++ {
+		s := o.ready[o.readyOffset%uint32(len(o.ready))]
+		o.readyOffset++
+		if accept == nil || accept(s) {
+			o.count(s)
+			return s
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+WithPerSlotRecovery replaces the all-or-nothing reset by independent recovery
+of every slot. When selection lands on a slot that is not servable, that
+slot's connection is redialed in the background and selection moves on to the
+next servable slot right away, so one wedged connection never stalls the
+others. Each slot recovers at most once per minimum retry interval and only
+one redial per slot is in flight at a time. If no slot is servable, a
+connection is returned anyway. It has no effect with WithWaitForReady or with
+automatic reset disabled.
+*/
+func WithPerSlotRecovery() Option {
+	return func(o *lb) {
+		o.perSlotRecovery = true
+	}
+}
+
+/*
+WithMaxConcurrentResets bounds how many slots per-slot recovery redials at the
+same time. Slots that need recovery while n redials are in flight are queued
+and redialed in turn as the running ones complete, so many connections failing
+at once do not cause a reconnect storm. Unlike WithResetCoordinator it limits
+a single pool and never drops a recovery, it only delays it. It requires
+WithPerSlotRecovery.
+*/
+func WithMaxConcurrentResets(n uint32) Option {
+	return func(o *lb) {
+		o.maxConcurrentResets = n
+	}
+}
+
+/*
+recoversPerSlot reports whether slots are recovered independently. The caller
+must hold the mutex.
+*/
+func (o *lb) recoversPerSlot() bool {
+	return o.perSlotRecovery && o.autoReset && !o.waitForReady
+}
+
+/*
+servableOrRecover reports whether the slot is servable, starting its recovery
+if it has not been for the recovery grace period. The caller must hold the
+mutex.
+*/
+func (o *lb) servableOrRecover(s *slot) bool {
+	servable := o.servable(s)
+	if !o.pastGrace(&s.unservableSince, !servable) {
+		return servable
+	}
+
+	o.recoverSlot(s)
+	return false
+}
+
+/*
+recoverSlot starts redialing the slot's connection in the background, unless
+that is already in flight, the slot recovered less than the minimum retry
+interval ago or the reset coordinator does not admit it. If the limit set by
+WithMaxConcurrentResets is reached, the slot is queued instead. The caller
+must hold the mutex.
+*/
+func (o *lb) recoverSlot(s *slot) {
+	now := time.Now().UTC()
+	if s.recovering || s.queued || now.Sub(s.lastRecovery) <= time.Duration(o.minRetryIntervalSeconds)*time.Second {
+		return
+	}
+
+	if o.maxConcurrentResets > 0 && o.redialing >= o.maxConcurrentResets {
+		s.queued = true
+		o.recoverQueue = append(o.recoverQueue, s)
+		return
+	}
+
+	if o.fatal != nil || !o.admitReset(1) {
+		return
+	}
+
+	s.recovering, s.lastRecovery = true, now
+	o.redialing++
+	go o.redialSlot(s, s.conn, o.redialer(s.index))
+}
+
+/*
+recoverQueued starts the recovery of queued slots until the limit set by
+WithMaxConcurrentResets is reached again. Slots that left the pool or became
+servable in the meantime are dropped from the queue. The caller must hold the
+mutex.
+*/
+func (o *lb) recoverQueued() {
+	for len(o.recoverQueue) > 0 && o.redialing < o.maxConcurrentResets {
+		s := o.recoverQueue[0]
+		o.recoverQueue[0] = nil
+		o.recoverQueue = o.recoverQueue[1:]
+		s.queued = false
+
+		if o.bySlot[s.conn] == s && !o.servable(s) {
+			o.recoverSlot(s)
+		}
+	}
+}
+
+/*
+redialSlot dials a replacement for old with redial without holding the mutex
+and installs it in the slot, unless the slot's connection changed or the slot
+left the pool in the meantime.
+*/
+func (o *lb) redialSlot(s *slot, old *grpc.ClientConn, redial func() (*grpc.ClientConn, error)) {
+	start := time.Now()
+	conn, err := redial()
+
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s.recovering = false
+	o.redialing--
+	if !o.closed {
+		defer o.recoverQueued()
+	}
+
+	o.classify(err)
+	if err != nil {
+		o.recordReset(ResetRecovery, start, []int{int(s.index)}, err)
+		o.log("Failed to recover connection " + itoa(s.index) + ": " + err.Error())
+		return
+	}
+
+	if o.closed || s.conn != old || o.bySlot[old] != s {
+		o.closeConn(conn)
+		return
+	}
+
+	o.detach(s)
+	if err := o.discard(old); err != nil {
+		o.log("Failed to close connection: " + err.Error())
+	}
+
+	o.attach(s, conn)
+	s.inFlight = 0
+	o.recordReset(ResetRecovery, start, []int{int(s.index)}, nil)
+	o.notifyReset([]int{int(s.index)})
+	o.notifyReleased()
+}
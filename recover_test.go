@@ -0,0 +1,158 @@
+package grpclb
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestPerSlotRecoveryDoesNotBlockOtherSlots(t *testing.T) {
+	ts := startServer(t)
+	gate := make(chan struct{})
+	var calls int32
+
+	l, err := New(3, 1, gatedFactory(ts, 3, gate, &calls), nil, WithPerSlotRecovery(), WithIdleGrace(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+
+	o.mutex.Lock()
+	wedged := o.slots[1].conn
+	healthy := []*grpc.ClientConn{o.slots[0].conn, o.slots[2].conn}
+	o.unlock()
+
+	for _, conn := range healthy {
+		conn.Connect()
+		waitState(t, conn, connectivity.Ready)
+	}
+
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		if conn := o.Get(); conn != healthy[0] && conn != healthy[1] {
+			t.Fatal("Get returned the wedged connection while healthy ones were available")
+		}
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("selection took %v while a slot was recovering", took)
+	}
+
+	eventually(t, "the wedged slot's redial to start", func() bool {
+		return atomic.LoadInt32(&calls) == 4
+	})
+
+	close(gate)
+	eventually(t, "the wedged slot to recover", func() bool {
+		o.mutex.Lock()
+		defer o.unlock()
+		return o.slots[1].conn != wedged
+	})
+
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Errorf("factory called %d times, want a single redial of the wedged slot", n)
+	}
+
+	if wedged.GetState() != connectivity.Shutdown {
+		t.Error("the replaced connection was not closed")
+	}
+}
+
+func TestSoleConnRecoveredOnFirstLockedGet(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 1, WithIdleGrace(0))
+	connectAll(t, o)
+
+	conn := o.slots[0].conn
+	for i := 0; i < 10; i++ {
+		if got := o.Get(); got != conn {
+			t.Fatal("Get did not return the ready connection")
+		}
+	}
+
+	o.mutex.Lock()
+	locked := o.useCount
+	o.unlock()
+	if locked != 0 {
+		t.Fatalf("%d selections went through the locked path, want all on the fast path", locked)
+	}
+
+	ts.server.Stop()
+	eventually(t, "the connection to fail", func() bool {
+		return !o.usable(conn.GetState())
+	})
+
+	o.allowReset()
+	if got := o.Get(); got == conn {
+		t.Fatal("the first locked Get handed out the failed connection again")
+	}
+
+	if n := ts.dialCount(); n != 2 {
+		t.Errorf("factory called %d times, want 2", n)
+	}
+}
+
+func TestMaxConcurrentResetsQueuesRecovery(t *testing.T) {
+	ts := startServer(t)
+	gate := make(chan struct{})
+	var calls, dialing, overlapped int32
+	dial := gatedFactory(ts, 3, gate, &calls)
+	factory := func() (*grpc.ClientConn, error) {
+		if atomic.AddInt32(&dialing, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		defer atomic.AddInt32(&dialing, -1)
+
+		return dial()
+	}
+
+	l, err := New(3, 1, factory, nil, WithPerSlotRecovery(), WithMaxConcurrentResets(1), WithIdleGrace(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o := l.(*lb)
+
+	o.mutex.Lock()
+	failed := []*grpc.ClientConn{o.slots[0].conn, o.slots[1].conn}
+	healthy := o.slots[2].conn
+	o.unlock()
+
+	healthy.Connect()
+	waitState(t, healthy, connectivity.Ready)
+	for i := 0; i < 10; i++ {
+		if o.Get() != healthy {
+			t.Fatal("Get did not return the only servable connection")
+		}
+	}
+
+	eventually(t, "the first recovery to dial", func() bool {
+		return atomic.LoadInt32(&calls) == 4
+	})
+
+	o.mutex.Lock()
+	redialing, queued := o.redialing, len(o.recoverQueue)
+	o.unlock()
+	if redialing != 1 || queued != 1 {
+		t.Fatalf("%d redials in flight and %d queued, want 1 and 1", redialing, queued)
+	}
+
+	close(gate)
+	eventually(t, "both failed slots to recover", func() bool {
+		o.mutex.Lock()
+		defer o.unlock()
+		return o.slots[0].conn != failed[0] && o.slots[1].conn != failed[1]
+	})
+
+	if n := atomic.LoadInt32(&calls); n != 5 {
+		t.Errorf("factory called %d times, want one redial per failed slot", n)
+	}
+
+	if atomic.LoadInt32(&overlapped) != 0 {
+		t.Error("redials ran at the same time despite a limit of 1")
+	}
+}
@@ -0,0 +1,94 @@
+package grpclb
+
+import "time"
+
+/*
+WithPoolMaxAge rebuilds the whole pool in the background every d, for example
+to pick up backend address changes through DNS without a resolver. The
+rebuild is a rolling Refresh, so the pool never goes without connections, and
+it is logged.
+*/
+func WithPoolMaxAge(d time.Duration) Option {
+	return func(o *lb) {
+		o.poolMaxAge = d
+	}
+}
+
+/*
+Refresh replaces every connection of the pool one at a time: a replacement is
+dialed without holding the mutex and swapped in before the connection it
+replaces is closed, so all but at most one connection keep serving throughout.
+Requests in flight on a replaced connection fail as with RecycleConn. Refresh
+stops at the first connection that fails to dial and returns the error; the
+connections replaced so far are kept. It also stops when the pool shrinks or
+is swapped while refreshing.
+*/
+func (o *lb) Refresh() error {
+	for i := uint32(0); ; i++ {
+		o.mutex.Lock()
+		if o.closed {
+			o.unlock()
+			return ErrClosed
+		}
+
+		if i >= o.size {
+			o.unlock()
+			return nil
+		}
+
+		s, redial := o.slots[i], o.redialer(i)
+		o.unlock()
+
+		conn, err := redial()
+		if err != nil {
+			return err
+		}
+
+		o.mutex.Lock()
+		if o.closed || i >= o.size || o.slots[i] != s {
+			closed := o.closed
+			o.unlock()
+			o.closeConn(conn)
+			if closed {
+				return ErrClosed
+			}
+
+			return nil
+		}
+
+		old := s.conn
+		o.detach(s)
+		o.attach(s, conn)
+		s.inFlight = 0
+		o.classify(nil)
+		o.notifyReset([]int{int(i)})
+		o.notifyReleased()
+		o.unlock()
+
+		if err := o.closeConn(old); err != nil {
+			o.log("Failed to close refreshed connection: " + err.Error())
+		}
+	}
+}
+
+/*
+refreshEvery refreshes the pool once per pool max age until the load balancer
+is closed.
+*/
+func (o *lb) refreshEvery() {
+	ticker := time.NewTicker(o.poolMaxAge)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.done:
+			return
+		case <-ticker.C:
+		}
+
+		o.log("Refreshing connections after reaching the pool max age")
+		if err := o.Refresh(); err != nil && err != ErrClosed {
+			o.log("Failed to refresh connections: " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package grpclb
+
+import "google.golang.org/grpc"
+
+// successRateWeight is the weight of the newest outcome in the moving average
+// of a connection's success rate, roughly a window of the last 20 requests.
+const successRateWeight = 0.05
+
+/*
+Report records the outcome of a request made on conn: nil for a success, the
+request's error otherwise. Callers may report nil for application level errors
+that say nothing about the health of the backend. Outcomes feed the per
+connection success rate exposed by Stats and drive outlier detection. Reports
+for connections that are no longer part of the pool are ignored.
+*/
+func (o *lb) Report(conn *grpc.ClientConn, err error) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	s, ok := o.bySlot[conn]
+	if !ok {
+		return
+	}
+
+	outcome := 1.0
+	if err != nil {
+		outcome = 0
+		s.failures++
+		s.consecutiveFailures++
+	} else {
+		s.successes++
+		s.consecutiveFailures = 0
+	}
+
+	s.success += (outcome - s.success) * successRateWeight
+
+	if o.outlier != nil {
+		o.detectOutlier(s)
+		o.checkCapacity()
+	}
+}
@@ -0,0 +1,86 @@
+package grpclb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+Reserve prepares the pool for a burst of traffic: it grows the pool to at least
+n connections, see Resize, and waits until n of them are Ready, asking idle
+ones to connect. The connections that are Ready already are counted first, and
+only as many others as are missing are waited for; with n of 0 Reserve returns
+right away. A pool that is already large enough is not shrunk; Resize it back
+down after the burst. If ctx is done first, the returned error reports how many
+connections became ready and wraps the context error.
+*/
+func (o *lb) Reserve(ctx context.Context, n uint32) error {
+	o.mutex.Lock()
+	if o.closed {
+		o.unlock()
+		return ErrClosed
+	}
+
+	grow := o.size < n
+	o.unlock()
+
+	if n == 0 {
+		return nil
+	}
+
+	if grow {
+		if err := o.Resize(n); err != nil {
+			return err
+		}
+	}
+
+	o.mutex.Lock()
+	var conns, others []*grpc.ClientConn
+	for _, s := range o.slots {
+		if s.conn.GetState() == connectivity.Ready {
+			conns = append(conns, s.conn)
+		} else {
+			others = append(others, s.conn)
+		}
+	}
+	o.unlock()
+
+	conns = append(conns, others...)
+	if uint32(len(conns)) < n {
+		return fmt.Errorf("pool shrank to %d connections while reserving %d", len(conns), n)
+	}
+	conns = conns[:n]
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		ready uint32
+	)
+
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *grpc.ClientConn) {
+			defer wg.Done()
+
+			if !waitReady(ctx, conn) {
+				return
+			}
+
+			mutex.Lock()
+			ready++
+			mutex.Unlock()
+		}(conn)
+	}
+
+	wg.Wait()
+
+	if ready < n {
+		return fmt.Errorf("%d of %d reserved connections ready: %w", ready, n, ctx.Err())
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package grpclb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestReserveGrowsAndWaitsForReady(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.Reserve(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.size != 3 {
+		t.Fatalf("size = %d after Reserve(3)", o.size)
+	}
+
+	for _, s := range o.slots {
+		if state := s.conn.GetState(); state != connectivity.Ready {
+			t.Errorf("connection %d is %v after Reserve, want Ready", s.index, state)
+		}
+	}
+}
+
+func TestReserveWaitsOnlyForMissingConns(t *testing.T) {
+	live, dead := startServer(t), startServer(t)
+	dead.server.Stop()
+
+	for _, c := range []struct {
+		name string
+		live int
+		n    uint32
+	}{
+		{"nothing to reserve", 0, 0},
+		{"enough ready connections", 1, 1},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dials := 0
+			factory := func() (*grpc.ClientConn, error) {
+				dials++
+				if dials <= c.live {
+					return live.factory()()
+				}
+				return dead.factory()()
+			}
+
+			l, err := New(3, 1, factory, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- l.Reserve(context.Background(), c.n)
+			}()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Reserve(%d): %v", c.n, err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Reserve(%d) blocked on connections that never become Ready", c.n)
+			}
+		})
+	}
+}
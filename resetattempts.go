@@ -0,0 +1,49 @@
+package grpclb
+
+import "google.golang.org/grpc"
+
+/*
+WithSlotResetAttempts gives every connection its own budget of dial attempts,
+one entry per index, for pools mixing flaky and stable backends. Whenever a
+connection is replaced, by a reset, per-slot recovery or RecycleConn, the
+factory is called up to that many times in a row before the replacement is
+given up and the error returned; the initial dials made by New are not
+retried, and neither are errors classified as fatal with WithErrorClassifier.
+An entry of 0 counts as 1, the default. New returns an error if the number of
+entries does not match the size. Connections added by Resize get the budget of
+the last connection.
+*/
+func WithSlotResetAttempts(attempts []uint32) Option {
+	return func(o *lb) {
+		o.resetAttempts = append([]uint32(nil), attempts...)
+	}
+}
+
+/*
+withAttempts wraps factory so that it is called up to the reset attempt budget
+of the connection at index, returning the last error if every attempt fails.
+An error the classifier set with WithErrorClassifier deems fatal is returned
+right away, since retrying it cannot succeed. The caller must hold the mutex.
+*/
+func (o *lb) withAttempts(index uint32, factory func() (*grpc.ClientConn, error)) func() (*grpc.ClientConn, error) {
+	if int(index) >= len(o.resetAttempts) || o.resetAttempts[index] <= 1 {
+		return factory
+	}
+
+	attempts, retryable := o.resetAttempts[index], o.retryable
+	return func() (*grpc.ClientConn, error) {
+		var err error
+		for i := uint32(0); i < attempts; i++ {
+			var conn *grpc.ClientConn
+			if conn, err = factory(); err == nil {
+				return conn, nil
+			}
+
+			if retryable != nil && !retryable(err) {
+				break
+			}
+		}
+
+		return nil, err
+	}
+}
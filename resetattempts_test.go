@@ -0,0 +1,58 @@
+package grpclb
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestSlotResetAttempts(t *testing.T) {
+	errFatal := errors.New("invalid target")
+	errFlaky := errors.New("connection refused")
+
+	for _, c := range []struct {
+		name string
+		err  error
+		want int32
+	}{
+		{"retryable errors use the budget", errFlaky, 4},
+		{"fatal errors are not retried", errFatal, 1},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ts := startServer(t)
+			dial := ts.factory()
+
+			var failing, calls int32
+			factory := func() (*grpc.ClientConn, error) {
+				if atomic.LoadInt32(&failing) == 0 {
+					return dial()
+				}
+
+				atomic.AddInt32(&calls, 1)
+				return nil, c.err
+			}
+
+			l, err := New(1, 1, factory, nil,
+				WithSlotResetAttempts([]uint32{4}),
+				WithErrorClassifier(func(err error) bool {
+					return !errors.Is(err, errFatal)
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			atomic.StoreInt32(&failing, 1)
+			if err := l.RecycleConn(0); !errors.Is(err, c.err) {
+				t.Fatalf("RecycleConn: got error %v, want %v", err, c.err)
+			}
+
+			if n := atomic.LoadInt32(&calls); n != c.want {
+				t.Errorf("factory called %d times, want %d", n, c.want)
+			}
+		})
+	}
+}
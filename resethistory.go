@@ -0,0 +1,120 @@
+package grpclb
+
+import "time"
+
+/*
+DefaultResetHistorySize is the number of reset events ResetHistory retains
+unless WithResetHistorySize changes it.
+*/
+const DefaultResetHistorySize = 16
+
+/*
+ResetTrigger names what caused a reset.
+*/
+type ResetTrigger string
+
+const (
+	// ResetAuto is a reset of the whole pool started by selection because
+	// the connections were unhealthy.
+	ResetAuto ResetTrigger = "auto"
+
+	// ResetForced is a reset of the whole pool requested through
+	// ForceReset.
+	ResetForced ResetTrigger = "force"
+
+	// ResetRecovery is the redial of a single connection by per-slot
+	// recovery.
+	ResetRecovery ResetTrigger = "recovery"
+
+	// ResetRecycle is the replacement of a single connection requested
+	// through RecycleConn.
+	ResetRecycle ResetTrigger = "recycle"
+)
+
+/*
+ResetEvent describes a completed reset attempt. Time is when the attempt
+started and Duration how long it took. Indices lists the connections it
+replaced or tried to replace. Err is nil if the attempt succeeded.
+*/
+type ResetEvent struct {
+	Time     time.Time
+	Trigger  ResetTrigger
+	Indices  []int
+	Err      error
+	Duration time.Duration
+}
+
+/*
+WithResetHistorySize sets how many of the most recent reset events
+ResetHistory retains, DefaultResetHistorySize by default. A size of 0 or less
+disables the history.
+*/
+func WithResetHistorySize(n int) Option {
+	return func(o *lb) {
+		if n < 0 {
+			n = 0
+		}
+
+		o.historySize = n
+	}
+}
+
+/*
+ResetHistory returns the most recent reset events, oldest first: the resets of
+the whole pool, whether automatic or forced, the per-slot recoveries and the
+connections replaced with RecycleConn. It complements the reset timestamps in
+PoolStats for post-incident analysis. The returned slice is a copy.
+*/
+func (o *lb) ResetHistory() []ResetEvent {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	events := make([]ResetEvent, 0, len(o.history))
+	events = append(events, o.history[o.historyNext:]...)
+	events = append(events, o.history[:o.historyNext]...)
+	for i := range events {
+		events[i].Indices = append([]int(nil), events[i].Indices...)
+	}
+
+	return events
+}
+
+/*
+recordReset adds a reset attempt that started at start to the history,
+overwriting the oldest event once the history is full, and to the event
+writer. The caller must hold the mutex.
+*/
+func (o *lb) recordReset(trigger ResetTrigger, start time.Time, indices []int, err error) {
+	event := ResetEvent{
+		Time:     start,
+		Trigger:  trigger,
+		Indices:  indices,
+		Err:      err,
+		Duration: time.Since(start),
+	}
+
+	o.emitReset(event)
+	if o.historySize == 0 {
+		return
+	}
+
+	if len(o.history) < o.historySize {
+		o.history = append(o.history, event)
+		return
+	}
+
+	o.history[o.historyNext] = event
+	o.historyNext = (o.historyNext + 1) % o.historySize
+}
+
+/*
+allIndices returns the indices of the first n connections.
+*/
+func allIndices(n uint32) []int {
+	indices := make([]int, n)
+	for i := range indices {
+		indices[i] = i
+	}
+
+	return indices
+}
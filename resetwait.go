@@ -0,0 +1,55 @@
+package grpclb
+
+import (
+	"context"
+	"time"
+)
+
+/*
+WithResetWait makes selection wait up to max for a background reset started by
+WithAsyncReset, instead of handing out one of the stale connections right away.
+Within that time the caller waits for the reset to complete and for the
+replacement it is about to be given to become Ready; if either takes longer,
+selection falls back to the connections at hand. It has no effect without
+asynchronous resets.
+*/
+func WithResetWait(max time.Duration) Option {
+	return func(o *lb) {
+		o.resetWait = max
+	}
+}
+
+/*
+awaitReset waits up to the reset wait for call to complete and then for the
+connection at the current offset to become Ready, counting the wait for
+GetResult. The mutex is released while waiting. The caller must hold the mutex.
+*/
+func (o *lb) awaitReset(call *resetCall) {
+	o.resetsWaited++
+	timer := time.NewTimer(o.resetWait)
+	defer timer.Stop()
+
+	deadline := time.Now().Add(o.resetWait)
+	o.mutex.Unlock()
+
+	select {
+	case <-call.done:
+	case <-timer.C:
+		o.mutex.Lock()
+		return
+	}
+
+	o.mutex.Lock()
+	if o.closed || call.err != nil {
+		return
+	}
+
+	conn := o.slots[o.offset].conn
+	o.mutex.Unlock()
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	waitReady(ctx, conn)
+	cancel()
+
+	o.mutex.Lock()
+}
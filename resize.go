@@ -0,0 +1,174 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+Resize changes the number of connections managed by the load balancer. Growing
+dials the missing connections with the factory function, reusing connections
+parked by an earlier shrink first. Shrinking removes the connections chosen by
+the policy set with WithShrinkPolicy, by default those with the highest
+indices; they are closed, or parked for the grace period set by
+WithResizeGrace. A removed connection with requests acquired through Acquire
+still in flight is drained first: it is closed once they have been released, or
+once the timeout set by WithDrainTimeout lapses, in which case the outstanding
+requests fail and the connection is logged; it is never parked. If the factory
+fails while growing, the connections created so far are kept and the error is
+returned. New connections inherit the priority of the last connection, see
+SetPriorities, and get a weight of 1. The size is bounded the same way as in
+New. Resize fails while a batched reset is in progress.
+*/
+func (o *lb) Resize(size uint32) error {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if err := o.checkSize(size); err != nil {
+		return err
+	}
+
+	defer o.fitToSize()
+
+	for o.size < size {
+		conn := o.unpark()
+		if conn == nil {
+			var err error
+			if conn, err = o.dial(o.size); err != nil {
+				return err
+			}
+		}
+
+		o.slots = append(o.slots, o.newSlot(o.size, conn))
+		o.size++
+	}
+
+	if o.size <= size {
+		return nil
+	}
+
+	var busy []*slot
+	for _, s := range o.shrink(size) {
+		o.detach(s)
+		o.notifyRemove(s, "shrink")
+		if s.inFlight > 0 {
+			busy = append(busy, s)
+		} else {
+			o.park(s.conn)
+		}
+	}
+
+	if len(busy) > 0 {
+		go o.drain(busy)
+	}
+
+	if o.offset >= o.size {
+		o.offset = 0
+	}
+
+	return nil
+}
+
+/*
+fitToSize fits the per connection settings to the current size. New
+connections get the priority, group, tags and reset attempt budget of the last
+connection and a weight of 1. The caller must hold the mutex.
+*/
+func (o *lb) fitToSize() {
+	if o.priorities != nil {
+		for len(o.priorities) < int(o.size) {
+			o.priorities = append(o.priorities, o.priorities[len(o.priorities)-1])
+		}
+
+		o.priorities = o.priorities[:o.size]
+	}
+
+	if o.groups != nil {
+		for len(o.groups) < int(o.size) {
+			o.groups = append(o.groups, o.groups[len(o.groups)-1])
+		}
+
+		o.groups = o.groups[:o.size]
+	}
+
+	if o.tags != nil {
+		for len(o.tags) < int(o.size) {
+			o.tags = append(o.tags, copyTags(o.tags[len(o.tags)-1]))
+		}
+
+		o.tags = o.tags[:o.size]
+	}
+
+	if o.resetAttempts != nil {
+		for len(o.resetAttempts) < int(o.size) {
+			o.resetAttempts = append(o.resetAttempts, o.resetAttempts[len(o.resetAttempts)-1])
+		}
+
+		o.resetAttempts = o.resetAttempts[:o.size]
+	}
+
+	if o.weights != nil {
+		for len(o.weights) < int(o.size) {
+			o.weights = append(o.weights, 1)
+		}
+
+		o.weights = o.weights[:o.size]
+		o.updateWeights()
+	}
+}
+
+/*
+park keeps a connection removed by a shrink open for the resize grace period,
+or closes it right away if there is none. The caller must hold the mutex.
+*/
+func (o *lb) park(conn *grpc.ClientConn) {
+	if o.resizeGrace <= 0 {
+		if err := o.closeConn(conn); err != nil {
+			o.log("Failed to close connection: " + err.Error())
+		}
+
+		return
+	}
+
+	parked := &slot{conn: conn}
+	parked.idleTimer = time.AfterFunc(o.resizeGrace, func() {
+		o.expire(parked)
+	})
+	o.parked = append(o.parked, parked)
+}
+
+/*
+unpark returns the most recently parked connection, or nil if no connection is
+parked. The caller must hold the mutex.
+*/
+func (o *lb) unpark() *grpc.ClientConn {
+	if len(o.parked) == 0 {
+		return nil
+	}
+
+	parked := o.parked[len(o.parked)-1]
+	o.parked = o.parked[:len(o.parked)-1]
+	parked.idleTimer.Stop()
+
+	return parked.conn
+}
+
+/*
+expire closes a parked connection whose grace period lapsed.
+*/
+func (o *lb) expire(parked *slot) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	for i, s := range o.parked {
+		if s == parked {
+			o.parked = append(o.parked[:i], o.parked[i+1:]...)
+			if err := o.closeConn(s.conn); err != nil {
+				o.log("Failed to close parked connection: " + err.Error())
+			}
+
+			return
+		}
+	}
+}
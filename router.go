@@ -0,0 +1,74 @@
+package grpclb
+
+import "google.golang.org/grpc"
+
+/*
+WithRouter sets the function GetRouted uses to map a routing key, such as a
+tenant or shard key, to the indices of the connections that may serve it. The
+function is called without holding the pool's mutex; indices out of range are
+ignored.
+*/
+func WithRouter(router func(routingKey string) []int) Option {
+	return func(o *lb) {
+		o.router = router
+	}
+}
+
+/*
+GetRouted selects a connection among the ones the router set with WithRouter
+returns for key, round-robin within that subset and preferring servable
+connections. Priorities and outlier ejection apply within the subset as long as
+they leave a connection of it to select. It returns nil if the subset holds no
+connection of the pool, or only leased ones. Without a router, GetRouted
+behaves like Get.
+*/
+func (o *lb) GetRouted(key string) *grpc.ClientConn {
+	if o.router == nil {
+		return o.Get()
+	}
+
+	indices := o.router(key)
+
+	o.mutex.Lock()
+	defer o.unlock()
+
+	routed := make([]bool, o.size)
+	for _, i := range indices {
+		if i >= 0 && i < int(o.size) {
+			routed[i] = true
+		}
+	}
+
+	inRoute := func(s *slot) bool {
+		return int(s.index) < len(routed) && routed[s.index]
+	}
+
+	s, err := o.nextWith(inRoute, func(accept func(s *slot) bool) *slot {
+		if s := o.pick(both(accept, o.servable)); s != nil {
+			return s
+		}
+
+		return o.pick(accept)
+	})
+	if err != nil {
+		return nil
+	}
+
+	if s == nil {
+		// The best priority tier or the ejections may leave out the whole
+		// subset; the route wins over them, but not over a lease.
+		if o.leased > 0 {
+			inRoute = both(inRoute, o.unleased)
+		}
+
+		if s = o.pick(both(inRoute, o.servable)); s == nil {
+			s = o.pick(inRoute)
+		}
+	}
+
+	if s == nil {
+		return nil
+	}
+
+	return s.conn
+}
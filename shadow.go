@@ -0,0 +1,99 @@
+package grpclb
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+WithShadow mirrors a fraction of the calls made through Call to a shadow
+connection created with factory, for example to try a new backend version
+with production traffic. For the given fraction of calls, between 0 and 1, fn
+additionally runs once on the shadow connection in the background; its result
+and error are discarded and never affect the call or the pool's health. The
+mirrored run keeps the values and the deadline of the call's context but is
+not canceled with it. The shadow connection is dialed by New and closed with
+the pool.
+*/
+func WithShadow(factory func() (*grpc.ClientConn, error), fraction float64) Option {
+	return func(o *lb) {
+		o.shadowFactory, o.shadowFraction = factory, fraction
+	}
+}
+
+/*
+dialShadow creates the shadow connection, if WithShadow is set.
+*/
+func (o *lb) dialShadow() error {
+	if o.shadowFactory == nil {
+		return nil
+	}
+
+	conn, err := o.shadowFactory()
+	if err != nil {
+		return err
+	}
+
+	o.shadow = conn
+	return nil
+}
+
+/*
+mirror runs fn on the shadow connection in the background for the configured
+fraction of calls.
+*/
+func (o *lb) mirror(ctx context.Context, fn func(ctx context.Context, conn *grpc.ClientConn) error) {
+	if o.shadow == nil {
+		return
+	}
+
+	o.mutex.Lock()
+	sampled := !o.closed && o.rand.Float64() < o.shadowFraction
+	o.unlock()
+
+	if !sampled {
+		return
+	}
+
+	var (
+		shadowCtx context.Context
+		cancel    context.CancelFunc
+	)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		shadowCtx, cancel = context.WithDeadline(detached{ctx}, deadline)
+	} else {
+		shadowCtx, cancel = context.WithCancel(detached{ctx})
+	}
+
+	go func() {
+		defer cancel()
+		_ = fn(shadowCtx, o.shadow)
+	}()
+}
+
+/*
+detached is a context that carries the values of its parent but not its
+cancellation or deadline.
+*/
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+func (d detached) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
@@ -0,0 +1,157 @@
+package grpclb
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+/*
+ShrinkPolicy selects which connections Resize removes when it shrinks the
+pool.
+*/
+type ShrinkPolicy int
+
+const (
+	// ShrinkHighestIndex removes the connections with the highest indices.
+	// It is the default.
+	ShrinkHighestIndex ShrinkPolicy = iota
+
+	// ShrinkLeastUsed removes the connections selected the fewest times, so
+	// the busiest connections are kept.
+	ShrinkLeastUsed
+
+	// ShrinkLeastHealthy removes the connections that are not servable
+	// first, then those with the lowest success rate.
+	ShrinkLeastHealthy
+)
+
+// shrinkPolicyNames maps the shrink policy names used in Config to policies.
+var shrinkPolicyNames = map[string]ShrinkPolicy{
+	"highest_index": ShrinkHighestIndex,
+	"least_used":    ShrinkLeastUsed,
+	"least_healthy": ShrinkLeastHealthy,
+}
+
+/*
+WithShrinkPolicy sets which connections Resize removes when shrinking. The
+default is ShrinkHighestIndex. Ties are broken by removing the highest index
+first. With another policy a removed connection may have a low index; the
+connection with the highest index that is kept then moves to that index, so
+the indices stay contiguous. It keeps its own per connection settings, such as
+its weight and priority, and a pin set on it with Pin; a pin on a removed
+connection is lifted.
+*/
+func WithShrinkPolicy(policy ShrinkPolicy) Option {
+	return func(o *lb) {
+		o.shrinkPolicy = policy
+	}
+}
+
+/*
+shrink removes slots according to the shrink policy until size are left,
+moving kept slots from beyond size into the indices that were freed together
+with their per connection settings and pin, and returns the removed slots. The
+caller must hold the mutex.
+*/
+func (o *lb) shrink(size uint32) []*slot {
+	candidates := append([]*slot(nil), o.slots...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return o.removeBefore(candidates[i], candidates[j])
+	})
+
+	removed := candidates[:o.size-size]
+	gone := make(map[*slot]bool, len(removed))
+	for _, s := range removed {
+		gone[s] = true
+	}
+
+	var movers []*slot
+	for _, s := range o.slots[size:] {
+		if !gone[s] {
+			movers = append(movers, s)
+		}
+	}
+
+	var pinned *slot
+	if o.pinned && o.pin < o.size {
+		pinned = o.slots[o.pin]
+	}
+
+	for i := uint32(0); i < size; i++ {
+		if gone[o.slots[i]] {
+			s := movers[len(movers)-1]
+			movers = movers[:len(movers)-1]
+			o.moveSettings(s.index, i)
+			s.index = i
+			o.slots[i] = s
+		}
+	}
+
+	if pinned != nil {
+		if !gone[pinned] {
+			o.pin = pinned.index
+		} else {
+			o.pinned = false
+			o.log("Unpinned selection from connection " + itoa(pinned.index) + ": it was removed by Resize")
+		}
+	}
+
+	for i := size; i < o.size; i++ {
+		o.slots[i] = nil
+	}
+
+	o.slots = o.slots[:size]
+	o.size = size
+	o.fastStale = true
+	return removed
+}
+
+/*
+moveSettings copies the per connection settings at index from to index to, for
+a slot moved there by shrink; fitToSize drops the entries beyond the new size
+afterwards. The caller must hold the mutex.
+*/
+func (o *lb) moveSettings(from, to uint32) {
+	if o.priorities != nil {
+		o.priorities[to] = o.priorities[from]
+	}
+
+	if o.groups != nil {
+		o.groups[to] = o.groups[from]
+	}
+
+	if o.tags != nil {
+		o.tags[to] = o.tags[from]
+	}
+
+	if o.resetAttempts != nil {
+		o.resetAttempts[to] = o.resetAttempts[from]
+	}
+
+	if o.weights != nil {
+		o.weights[to] = o.weights[from]
+	}
+}
+
+/*
+removeBefore reports whether shrinking removes a before b. The caller must
+hold the mutex.
+*/
+func (o *lb) removeBefore(a, b *slot) bool {
+	switch o.shrinkPolicy {
+	case ShrinkLeastUsed:
+		if ua, ub := atomic.LoadUint64(&a.uses), atomic.LoadUint64(&b.uses); ua != ub {
+			return ua < ub
+		}
+	case ShrinkLeastHealthy:
+		if sa, sb := o.servable(a), o.servable(b); sa != sb {
+			return !sa
+		}
+
+		if a.success != b.success {
+			return a.success < b.success
+		}
+	}
+
+	return a.index > b.index
+}
@@ -0,0 +1,74 @@
+package grpclb
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestShrinkMovesSettingsWithSlot(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 3,
+		WithShrinkPolicy(ShrinkLeastUsed),
+		WithStrategy(WeightedRandom),
+		WithWeights([]uint32{1, 2, 3}),
+		WithPriorities([]int{0, 1, 2}),
+		WithGroups([]string{"a", "b", "c"}),
+		WithTags([]map[string]string{{"host": "a"}, {"host": "b"}, {"host": "c"}}),
+		WithSlotResetAttempts([]uint32{1, 2, 3}),
+	)
+
+	atomic.StoreUint64(&o.slots[1].uses, 10)
+	atomic.StoreUint64(&o.slots[2].uses, 10)
+	moved := o.slots[2].conn
+	if err := o.Pin(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Resize(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.slots[0].conn != moved || o.slots[0].index != 0 {
+		t.Fatal("the connection at index 2 did not move to the freed index 0")
+	}
+
+	cfg := o.Config()
+	for _, c := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"weights", cfg.Weights, []uint32{3, 2}},
+		{"priorities", cfg.Priorities, []int{2, 1}},
+		{"groups", cfg.Groups, []string{"c", "b"}},
+		{"tags", cfg.Tags, []map[string]string{{"host": "c"}, {"host": "b"}}},
+		{"reset attempts", cfg.SlotResetAttempts, []uint32{3, 2}},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	if !o.pinned || o.pin != 0 {
+		t.Errorf("pin = %d (active %v), want it to follow the connection to 0", o.pin, o.pinned)
+	}
+}
+
+func TestShrinkLiftsPinOfRemovedConn(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 3, WithShrinkPolicy(ShrinkLeastUsed))
+
+	atomic.StoreUint64(&o.slots[1].uses, 10)
+	atomic.StoreUint64(&o.slots[2].uses, 10)
+	if err := o.Pin(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Resize(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.pinned {
+		t.Fatal("the pin survived the removal of the pinned connection")
+	}
+}
@@ -0,0 +1,95 @@
+package grpclb
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+Shutdown closes the load balancer gracefully within ctx's deadline. Like Close
+it stops handing out connections right away, but it waits for the requests
+acquired through Acquire on all connections to be released before closing
+them. Connections that still have requests in flight when ctx expires are
+closed anyway, and the returned error lists their indices and wraps ctx's
+error. Errors from closing the connections are reported as well. Shutting down
+a closed load balancer is a no-op.
+*/
+func (o *lb) Shutdown(ctx context.Context) error {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	o.markClosed()
+
+	var ctxErr error
+	for ctxErr == nil && o.busy() {
+		released := o.released
+		o.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+		case <-released:
+		}
+
+		o.mutex.Lock()
+	}
+
+	var forced []int
+	overflow := 0
+	for _, s := range o.slots {
+		if s.inFlight > 0 {
+			forced = append(forced, int(s.index))
+		}
+	}
+
+	for _, s := range o.overflow {
+		if s.inFlight > 0 {
+			overflow++
+		}
+	}
+
+	closeErr := o.closeConns()
+
+	var err error
+	if len(forced) > 0 || overflow > 0 {
+		msg := fmt.Sprintf("force closed connections %v", forced)
+		if overflow > 0 {
+			msg += fmt.Sprintf(" and %d overflow connections", overflow)
+		}
+
+		err = fmt.Errorf("%s with requests in flight: %w", msg, ctxErr)
+	}
+
+	switch {
+	case closeErr == nil:
+		return err
+	case err == nil:
+		return closeErr
+	default:
+		return fmt.Errorf("%w; failed to close connections: %v", err, closeErr)
+	}
+}
+
+/*
+busy reports whether any connection of the pool has requests in flight. The
+caller must hold the mutex.
+*/
+func (o *lb) busy() bool {
+	for _, s := range o.slots {
+		if s.inFlight > 0 {
+			return true
+		}
+	}
+
+	for _, s := range o.overflow {
+		if s.inFlight > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+// slowStartFloor is the share of its regular traffic a connection receives
+// right after becoming Ready with slow start.
+const slowStartFloor = 0.1
+
+/*
+WithSlowStart ramps up the traffic a connection receives after it becomes
+Ready, so a freshly dialed backend connection can warm up before it takes its
+full share. The connection starts at a tenth of the share its strategy and
+weight give it and grows linearly to the full share over window. A connection
+that loses Ready and reaches it again starts over. It applies to every
+strategy; while every connection is warming up at the same pace, as after New
+or a reset of the whole pool, the distribution is unchanged.
+*/
+func WithSlowStart(window time.Duration) Option {
+	return func(o *lb) {
+		o.slowStart = window
+	}
+}
+
+/*
+trackWarmup records when the slot's connection became Ready. The caller must
+hold the mutex.
+*/
+func trackWarmup(s *slot, state connectivity.State) {
+	if state != connectivity.Ready {
+		s.readySince = time.Time{}
+	} else if s.readySince.IsZero() {
+		s.readySince = time.Now()
+	}
+}
+
+/*
+warmth returns the share of its regular traffic the slot receives at now, 1
+once it is past the slow start window. The caller must hold the mutex.
+*/
+func (o *lb) warmth(s *slot, now time.Time) float64 {
+	if s.readySince.IsZero() {
+		return 1
+	}
+
+	elapsed := now.Sub(s.readySince)
+	if elapsed >= o.slowStart {
+		return 1
+	}
+
+	return slowStartFloor + (1-slowStartFloor)*float64(elapsed)/float64(o.slowStart)
+}
+
+/*
+warm draws for every slot in its slow start window whether it takes part in
+this selection, with a probability equal to its warmth, and returns a filter
+accepting the slots that do, or nil if every slot does. The caller must hold
+the mutex.
+*/
+func (o *lb) warm() func(s *slot) bool {
+	now := time.Now()
+	var cold map[*slot]bool
+	for _, s := range o.slots {
+		if w := o.warmth(s, now); w < 1 && o.rand.Float64() >= w {
+			if cold == nil {
+				cold = make(map[*slot]bool)
+			}
+
+			cold[s] = true
+		}
+	}
+
+	if cold == nil {
+		return nil
+	}
+
+	return func(s *slot) bool {
+		return !cold[s]
+	}
+}
@@ -0,0 +1,44 @@
+package grpclb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlowStartRampsTraffic(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 2, WithSlowStart(time.Hour))
+	connectAll(t, o)
+
+	// A connection at warmth w competes like one with w times the weight of
+	// the warm one, so it gets w/(1+w) of the selections: about 9%, 35% and
+	// 50% at the start, in the middle and past the end of the window.
+	warm, fresh := o.slots[0], o.slots[1]
+	for _, c := range []struct {
+		name     string
+		age      time.Duration
+		min, max int
+	}{
+		{"just ready", 0, 50, 140},
+		{"half way", 30 * time.Minute, 290, 420},
+		{"past the window", 2 * time.Hour, 450, 550},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			o.mutex.Lock()
+			warm.readySince = time.Now().Add(-2 * time.Hour)
+			fresh.readySince = time.Now().Add(-c.age)
+			o.unlock()
+
+			n := 0
+			for i := 0; i < 1000; i++ {
+				if o.Get() == fresh.conn {
+					n++
+				}
+			}
+
+			if n < c.min || n > c.max {
+				t.Errorf("the new connection got %d of 1000 selections, want %d to %d", n, c.min, c.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package grpclb
+
+import (
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+WithStateDurations makes the state watcher account for the time every
+connection spends in each connectivity state, exposed by StateDurations. A
+connection that spends a lot of its time in TransientFailure or Connecting
+points at a flapping backend.
+*/
+func WithStateDurations() Option {
+	return func(o *lb) {
+		o.stateDurations = true
+	}
+}
+
+/*
+StateDurations returns, for every connection by index, the time it spent in
+each connectivity state since it was created, including the time spent in its
+current state so far. It returns nil unless WithStateDurations is set.
+*/
+func (o *lb) StateDurations() []map[connectivity.State]time.Duration {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if !o.stateDurations {
+		return nil
+	}
+
+	now := time.Now()
+	durations := make([]map[connectivity.State]time.Duration, len(o.slots))
+	for i, s := range o.slots {
+		durations[i] = make(map[connectivity.State]time.Duration, len(s.durations)+1)
+		for state, d := range s.durations {
+			durations[i][state] = d
+		}
+
+		if !s.stateSince.IsZero() {
+			durations[i][s.state] += now.Sub(s.stateSince)
+		}
+	}
+
+	return durations
+}
+
+/*
+recordState closes the period the slot's connection spent in its previous
+state and starts one for state. The caller must hold the mutex.
+*/
+func (o *lb) recordState(s *slot, state connectivity.State, now time.Time) {
+	if !s.stateSince.IsZero() {
+		if s.durations == nil {
+			s.durations = make(map[connectivity.State]time.Duration)
+		}
+
+		s.durations[s.state] += now.Sub(s.stateSince)
+	}
+
+	s.state, s.stateSince = state, now
+}
@@ -0,0 +1,214 @@
+package grpclb
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+ReadOnlyLB exposes the observation methods of a load balancer only. It can be
+handed to code that should watch the pool without being able to close or
+otherwise change it.
+*/
+type ReadOnlyLB interface {
+	Stats() PoolStats
+	ReadyCount() int
+}
+
+/*
+PoolStats is a snapshot of the state of the load balancer. LastReset is the
+time of the last reset attempt, LastSuccessfulReset and LastFailedReset the
+times of the last attempts that succeeded and failed; a growing gap between
+LastReset and LastSuccessfulReset indicates ongoing backend trouble.
+Successes and Failures sum the outcomes reported for the current connections.
+Closed is set once Close or Shutdown has been called, including while Shutdown
+waits for requests to drain. Dials summarizes the time the factory took, which
+tells slow reconnects apart from slow requests; it describes the whole pool, so
+group stats leave it empty. The stats encode to JSON with stable field names,
+for periodic snapshots sent to a log aggregator.
+*/
+type PoolStats struct {
+	Size                uint32      `json:"size"`
+	Ready               int         `json:"ready"`
+	InFlight            int64       `json:"inFlight"`
+	BytesInFlight       int64       `json:"bytesInFlight"`
+	UseCount            uint64      `json:"useCount"`
+	Successes           uint64      `json:"successes"`
+	Failures            uint64      `json:"failures"`
+	LastReset           time.Time   `json:"lastReset"`
+	LastSuccessfulReset time.Time   `json:"lastSuccessfulReset"`
+	LastFailedReset     time.Time   `json:"lastFailedReset"`
+	Overflow            int         `json:"overflow"`
+	Closed              bool        `json:"closed"`
+	Dials               DialStats   `json:"dials"`
+	Conns               []ConnStats `json:"conns"`
+}
+
+/*
+ConnStats is a snapshot of the state of a single connection in the pool. Uses
+counts the times the connection was selected and BytesInFlight sums the payload
+sizes passed to AcquireBytes for its open requests. Successes and Failures
+count the outcomes passed to Report since the connection was created,
+SuccessRate is their exponentially weighted moving average, which favours
+recent outcomes and starts at 1. Utilization is the last utilization reported
+through ReportTrailer with WithORCA, decayed by its age. Ejected is set while
+outlier detection keeps the connection out of rotation. In JSON, State is the
+state's name and RTT a duration string such as "1.5ms".
+*/
+type ConnStats struct {
+	Index         uint32             `json:"index"`
+	State         connectivity.State `json:"state"`
+	InFlight      int64              `json:"inFlight"`
+	BytesInFlight int64              `json:"bytesInFlight"`
+	Uses          uint64             `json:"uses"`
+	RTT           time.Duration      `json:"rtt"`
+	Successes     uint64             `json:"successes"`
+	Failures      uint64             `json:"failures"`
+	SuccessRate   float64            `json:"successRate"`
+	Utilization   float64            `json:"utilization"`
+	Ejected       bool               `json:"ejected"`
+}
+
+/*
+MarshalJSON implements json.Marshaler.
+*/
+func (s PoolStats) MarshalJSON() ([]byte, error) {
+	type plain PoolStats
+	return json.Marshal(plain(s))
+}
+
+/*
+MarshalJSON implements json.Marshaler.
+*/
+func (c ConnStats) MarshalJSON() ([]byte, error) {
+	type plain ConnStats
+	return json.Marshal(struct {
+		plain
+		State string   `json:"state"`
+		RTT   Duration `json:"rtt"`
+	}{plain(c), c.State.String(), Duration(c.RTT)})
+}
+
+/*
+Stats returns a snapshot of the pool and each of its connections, taken under
+the mutex so that it is internally consistent.
+*/
+func (o *lb) Stats() PoolStats {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	stats := o.stats(o.slots)
+	stats.UseCount = o.useCount + atomic.LoadUint64(&o.fastUseCount)
+	stats.Overflow = len(o.overflow)
+	stats.Closed = o.closed
+	stats.Dials = o.dials()
+	for _, s := range o.overflow {
+		stats.InFlight += s.inFlight
+		stats.BytesInFlight += s.bytesInFlight
+	}
+
+	return stats
+}
+
+/*
+stats builds the snapshot of the given slots. UseCount is the sum of their
+uses; overflow connections are not included. The caller must hold the mutex.
+*/
+func (o *lb) stats(slots []*slot) PoolStats {
+	stats := PoolStats{
+		Size:                uint32(len(slots)),
+		LastReset:           o.lastReset,
+		LastSuccessfulReset: o.lastSuccessfulReset,
+		LastFailedReset:     o.lastFailedReset,
+		Conns:               make([]ConnStats, 0, len(slots)),
+	}
+
+	now := time.Now()
+	for _, s := range slots {
+		state := s.conn.GetState()
+		if state == connectivity.Ready {
+			stats.Ready++
+		}
+
+		stats.InFlight += s.inFlight
+		stats.BytesInFlight += s.bytesInFlight
+		uses := atomic.LoadUint64(&s.uses)
+		stats.UseCount += uses
+		stats.Successes += s.successes
+		stats.Failures += s.failures
+		stats.Conns = append(stats.Conns, ConnStats{
+			Index:         s.index,
+			State:         state,
+			InFlight:      s.inFlight,
+			BytesInFlight: s.bytesInFlight,
+			Uses:          uses,
+			RTT:           s.rtt,
+			Successes:     s.successes,
+			Failures:      s.failures,
+			SuccessRate:   s.success,
+			Utilization:   utilizationOf(s, now),
+			Ejected:       o.ejected(s, now),
+		})
+	}
+
+	return stats
+}
+
+/*
+ReadyCount returns the number of connections that are currently Ready.
+*/
+func (o *lb) ReadyCount() int {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	count := 0
+	for _, s := range o.slots {
+		if s.conn.GetState() == connectivity.Ready {
+			count++
+		}
+	}
+
+	return count
+}
+
+/*
+UnhealthyIndices returns the indices of the connections whose state is not
+usable, as defined by WithUsablePredicate. Pass them to RecycleConn to recreate
+only the bad connections.
+*/
+func (o *lb) UnhealthyIndices() []int {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	var indices []int
+	for _, s := range o.slots {
+		if !o.usable(s.conn.GetState()) {
+			indices = append(indices, int(s.index))
+		}
+	}
+
+	return indices
+}
+
+/*
+ReadOnly returns a view of the load balancer that only exposes its observation
+methods, so it can be shared without risking an accidental Close.
+*/
+func (o *lb) ReadOnly() ReadOnlyLB {
+	return readOnly{lb: o}
+}
+
+type readOnly struct {
+	lb *lb
+}
+
+func (r readOnly) Stats() PoolStats {
+	return r.lb.Stats()
+}
+
+func (r readOnly) ReadyCount() int {
+	return r.lb.ReadyCount()
+}
@@ -0,0 +1,185 @@
+package grpclb
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+/*
+ConnStore shares connections between load balancers in the same process that
+point at the same target, so that overlapping pools do not open duplicate
+connections. Connections are reference counted and only closed once the last
+pool holding them releases them. A store is safe for concurrent use.
+*/
+type ConnStore struct {
+	mutex   sync.Mutex
+	entries map[string][]*storedConn
+	byConn  map[*grpc.ClientConn]*storedConn
+}
+
+/*
+storedConn is a connection in a store together with the pools holding it. An
+invalidated connection is no longer handed out but stays open for its holders
+until they release it.
+*/
+type storedConn struct {
+	key     string
+	conn    *grpc.ClientConn
+	owners  map[*lb]struct{}
+	invalid bool
+}
+
+/*
+NewConnStore creates an empty connection store.
+*/
+func NewConnStore() *ConnStore {
+	return &ConnStore{
+		entries: make(map[string][]*storedConn),
+		byConn:  make(map[*grpc.ClientConn]*storedConn),
+	}
+}
+
+/*
+WithConnStore makes the load balancer obtain its connections from store, shared
+with every other load balancer using the same store and key. The key identifies
+the target: pools must only share a key if their factories create
+interchangeable connections. A pool never holds the same connection twice, so
+pools of size n sharing a key share n connections. A reset invalidates the
+replaced connection for every pool, without closing it under the pools still
+using it, and dials a fresh one rather than reusing a shared connection that
+may suffer from the same outage. After Swap the pool's new connections are its
+own. Overflow connections are shared like any other, and so are the
+connections of a pool created with Clone.
+*/
+func WithConnStore(store *ConnStore, key string) Option {
+	return func(o *lb) {
+		o.store, o.storeKey, o.shared = store, key, store != nil
+	}
+}
+
+/*
+acquire returns a valid connection for key that owner does not hold yet,
+preferring the least shared one, or dials a new one with factory.
+*/
+func (c *ConnStore) acquire(owner *lb, key string, factory func() (*grpc.ClientConn, error)) (*grpc.ClientConn, error) {
+	c.mutex.Lock()
+	var best *storedConn
+	for _, e := range c.entries[key] {
+		if _, held := e.owners[owner]; held || e.invalid {
+			continue
+		}
+
+		if best == nil || len(e.owners) < len(best.owners) {
+			best = e
+		}
+	}
+
+	if best != nil {
+		best.owners[owner] = struct{}{}
+		c.mutex.Unlock()
+		return best.conn, nil
+	}
+	c.mutex.Unlock()
+
+	return c.add(owner, key, factory)
+}
+
+/*
+add dials a new connection for key with factory and hands it to owner, making
+it available to other pools as well.
+*/
+func (c *ConnStore) add(owner *lb, key string, factory func() (*grpc.ClientConn, error)) (*grpc.ClientConn, error) {
+	conn, err := dialWith(factory)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &storedConn{key: key, conn: conn, owners: map[*lb]struct{}{owner: {}}}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[key] = append(c.entries[key], e)
+	c.byConn[conn] = e
+	return conn, nil
+}
+
+/*
+release drops owner's reference to conn and closes it once no pool holds it
+anymore. A connection that does not come from the store is closed right away.
+*/
+func (c *ConnStore) release(owner *lb, conn *grpc.ClientConn) error {
+	c.mutex.Lock()
+	e, ok := c.byConn[conn]
+	if ok {
+		delete(e.owners, owner)
+		if len(e.owners) > 0 {
+			c.mutex.Unlock()
+			return nil
+		}
+
+		c.remove(e)
+	}
+	c.mutex.Unlock()
+
+	return conn.Close()
+}
+
+/*
+invalidate stops conn from being handed out to further pools. It is a no-op
+for connections that do not come from the store.
+*/
+func (c *ConnStore) invalidate(conn *grpc.ClientConn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, ok := c.byConn[conn]; ok {
+		e.invalid = true
+	}
+}
+
+/*
+remove deletes e from the store. The caller must hold the store's mutex.
+*/
+func (c *ConnStore) remove(e *storedConn) {
+	delete(c.byConn, e.conn)
+
+	entries := c.entries[e.key]
+	for i, other := range entries {
+		if other == e {
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+
+	if len(entries) == 0 {
+		delete(c.entries, e.key)
+	} else {
+		c.entries[e.key] = entries
+	}
+}
+
+/*
+closeConn closes a connection of the pool, or releases it to the store the
+pool shares its connections through.
+*/
+func (o *lb) closeConn(conn *grpc.ClientConn) error {
+	if o.store == nil {
+		return conn.Close()
+	}
+
+	return o.store.release(o, conn)
+}
+
+/*
+discard closes a connection that is being replaced because it is unhealthy.
+With a store it is invalidated first, so that no other pool picks it up.
+*/
+func (o *lb) discard(conn *grpc.ClientConn) error {
+	if o.store != nil {
+		o.store.invalidate(conn)
+	}
+
+	return o.closeConn(conn)
+}
@@ -0,0 +1,97 @@
+package grpclb
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+connSet returns the connections of the pool.
+*/
+func connSet(o *lb) map[*grpc.ClientConn]bool {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	conns := make(map[*grpc.ClientConn]bool, len(o.slots))
+	for _, s := range o.slots {
+		conns[s.conn] = true
+	}
+
+	return conns
+}
+
+func TestConnStoreSharesAcrossPools(t *testing.T) {
+	ts := startServer(t)
+	store := NewConnStore()
+	a := newTestLB(t, ts, 2, WithConnStore(store, "target"))
+	b := newTestLB(t, ts, 2, WithConnStore(store, "target"))
+
+	if n := ts.dialCount(); n != 2 {
+		t.Fatalf("two pools of 2 sharing a key dialed %d connections, want 2", n)
+	}
+
+	shared := connSet(a)
+	for conn := range connSet(b) {
+		if !shared[conn] {
+			t.Fatal("the pools do not share their connections")
+		}
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for conn := range shared {
+		if conn.GetState() == connectivity.Shutdown {
+			t.Fatal("closing one pool closed a connection the other still holds")
+		}
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for conn := range shared {
+		if conn.GetState() != connectivity.Shutdown {
+			t.Fatal("a connection stayed open after its last pool closed")
+		}
+	}
+}
+
+func TestCloneSharesConnStore(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 2, WithConnStore(NewConnStore(), "target"))
+
+	l, err := o.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := l.(*lb)
+
+	original := connSet(o)
+	for conn := range connSet(clone) {
+		if !original[conn] {
+			t.Fatal("the clone dialed its own connections despite the store")
+		}
+	}
+
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if conn := clone.Get(); conn == nil || conn.GetState() == connectivity.Shutdown {
+		t.Fatal("closing the original closed the clone's connections")
+	}
+
+	if err := clone.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for conn := range original {
+		if conn.GetState() != connectivity.Shutdown {
+			t.Fatal("a shared connection stayed open after both pools closed")
+		}
+	}
+}
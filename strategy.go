@@ -0,0 +1,182 @@
+package grpclb
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+/*
+Strategy selects how the load balancer picks a connection.
+*/
+type Strategy int
+
+const (
+	// RoundRobin hands out the connections in turn. It is the default.
+	RoundRobin Strategy = iota
+
+	// WeightedRandom picks connections at random with a probability
+	// proportional to their weight, scaled down by their reported success
+	// rate. Unlike deterministic weighted round-robin it avoids many clients
+	// selecting the same connections in lockstep.
+	WeightedRandom
+
+	// WeightedLeastConnections picks the connection with the fewest requests
+	// in flight relative to its weight, so that capacity differences are
+	// respected while following the actual load. Only requests acquired
+	// through Acquire count as in flight.
+	WeightedLeastConnections
+
+	// LeastBytesInFlight picks the connection with the fewest payload bytes
+	// in flight, for workloads where a single large request outweighs many
+	// small ones. Only requests acquired through AcquireBytes count, with the
+	// size passed to it.
+	LeastBytesInFlight
+)
+
+// weightedRandomAttempts bounds how many samples a weighted random pick draws
+// before falling back to round-robin over the accepted connections.
+const weightedRandomAttempts = 8
+
+/*
+WithStrategy sets the selection strategy. The default is RoundRobin.
+*/
+func WithStrategy(strategy Strategy) Option {
+	return func(o *lb) {
+		o.strategy = strategy
+	}
+}
+
+/*
+WithWeights assigns a weight to every connection, one entry per index, used by
+the weighted strategies. New returns an error if the number of weights does
+not match the size. Without weights every connection weighs 1.
+*/
+func WithWeights(weights []uint32) Option {
+	return func(o *lb) {
+		o.weights, o.weightsSet = append([]uint32(nil), weights...), true
+	}
+}
+
+/*
+SetWeights replaces the weights of the connections at runtime, one entry per
+index. It returns an error if the number of weights does not match the current
+size.
+*/
+func (o *lb) SetWeights(weights []uint32) error {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if o.closed {
+		return ErrClosed
+	}
+
+	if len(weights) != int(o.size) {
+		return errors.New("weights must have one entry per connection")
+	}
+
+	o.weights, o.weightsSet = append([]uint32(nil), weights...), true
+	o.updateWeights()
+	return nil
+}
+
+/*
+usesWeights reports whether the strategy takes the connection weights into
+account.
+*/
+func (o *lb) usesWeights() bool {
+	return o.strategy == WeightedRandom || o.strategy == WeightedLeastConnections
+}
+
+/*
+pickLeastLoaded picks the accepted slot with the fewest requests in flight per
+unit of weight, preferring servable slots. A slot with a weight of 0 is only
+picked if every accepted slot weighs 0. The caller must hold the mutex.
+*/
+func (o *lb) pickLeastLoaded(accept func(s *slot) bool) *slot {
+	load := func(s *slot) float64 {
+		w := o.weights[s.index]
+		if w == 0 {
+			return math.Inf(1)
+		}
+
+		return float64(s.inFlight) / float64(w)
+	}
+
+	if s := o.pickMin(both(accept, o.servable), load); s != nil {
+		return s
+	}
+
+	return o.pickMin(accept, load)
+}
+
+/*
+pickLeastBytes picks the accepted slot with the fewest bytes in flight,
+preferring servable slots. The caller must hold the mutex.
+*/
+func (o *lb) pickLeastBytes(accept func(s *slot) bool) *slot {
+	bytes := func(s *slot) float64 {
+		return float64(s.bytesInFlight)
+	}
+
+	if s := o.pickMin(both(accept, o.servable), bytes); s != nil {
+		return s
+	}
+
+	return o.pickMin(accept, bytes)
+}
+
+/*
+updateWeights recomputes the prefix sums of the weights used for weighted
+random sampling. The caller must hold the mutex.
+*/
+func (o *lb) updateWeights() {
+	if o.weights == nil {
+		o.cumWeights = nil
+		return
+	}
+
+	o.cumWeights = make([]uint64, len(o.weights))
+	total := uint64(0)
+	for i, w := range o.weights {
+		total += uint64(w)
+		o.cumWeights[i] = total
+	}
+}
+
+/*
+pickWeightedRandom samples a slot with a probability proportional to its weight
+and keeps it with a probability equal to its success rate, so failing
+connections are picked less often. Slots that are not servable or not accepted
+are rejected. It returns nil if no sample was kept within a few attempts. The
+caller must hold the mutex.
+*/
+func (o *lb) pickWeightedRandom(accept func(s *slot) bool) *slot {
+	if len(o.cumWeights) == 0 {
+		return nil
+	}
+
+	total := o.cumWeights[len(o.cumWeights)-1]
+	if total == 0 {
+		return nil
+	}
+
+	for i := 0; i < weightedRandomAttempts; i++ {
+		n := uint64(o.rand.Int63n(int64(total)))
+		index := sort.Search(len(o.cumWeights), func(i int) bool {
+			return o.cumWeights[i] > n
+		})
+
+		s := o.slots[index]
+		if !o.servable(s) || (accept != nil && !accept(s)) {
+			continue
+		}
+
+		if o.rand.Float64() < s.success {
+			o.count(s)
+			return s
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package grpclb
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestWeightedLeastConnectionsFollowsWeights(t *testing.T) {
+	ts := startServer(t)
+	o := newTestLB(t, ts, 2, WithStrategy(WeightedLeastConnections), WithWeights([]uint32{1, 3}))
+	connectAll(t, o)
+
+	light, heavy := o.slots[0].conn, o.slots[1].conn
+	held := map[*grpc.ClientConn][]*PooledConn{}
+	for i := 0; i < 40; i++ {
+		pc, err := o.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		held[pc.Conn()] = append(held[pc.Conn()], pc)
+	}
+
+	if n, m := len(held[light]), len(held[heavy]); n != 10 || m != 30 {
+		t.Fatalf("in flight = %d and %d, want 10 and 30 for weights 1 and 3", n, m)
+	}
+
+	for _, pc := range held[heavy][:6] {
+		pc.Done()
+	}
+
+	for i := 0; i < 6; i++ {
+		pc, err := o.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pc.Conn() != heavy {
+			t.Fatalf("acquisition %d did not go to the connection whose requests finished", i)
+		}
+	}
+}
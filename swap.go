@@ -0,0 +1,160 @@
+package grpclb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+Swap replaces every connection of the pool at once, for reconfigurations such
+as a new target set, size or dial options. The new connections are dialed with
+factory in the background while the old set keeps serving, then swapped in
+atomically; factory is used for all later dials as well. The old connections
+are closed once their in-flight requests acquired through Acquire have been
+released, or once the timeout set by WithDrainTimeout lapses. If any new
+connection fails to dial, the pool is left unchanged and the error is returned.
+*/
+func (o *lb) Swap(factory func() (*grpc.ClientConn, error), size uint32) error {
+	if factory == nil {
+		return errors.New("factory can't be nil")
+	}
+
+	o.mutex.Lock()
+	err := o.checkSize(size)
+	o.unlock()
+	if err != nil {
+		return err
+	}
+
+	conns := make([]*grpc.ClientConn, size)
+	for i := range conns {
+		if conns[i], err = dialWith(o.timed(uint32(i), factory)); err != nil {
+			o.closeAll(conns)
+			return err
+		}
+	}
+
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if err := o.checkSize(size); err != nil {
+		o.closeAll(conns)
+		return err
+	}
+
+	old := o.slots
+	for _, s := range old {
+		o.detach(s)
+	}
+
+	for _, s := range o.parked {
+		s.idleTimer.Stop()
+		o.closeConn(s.conn)
+	}
+	o.parked = nil
+
+	o.factory = factory
+	o.shared = false
+	o.fatal = nil
+	o.size = size
+	o.offset = 0
+	o.slots = make([]*slot, size)
+	for i, conn := range conns {
+		o.slots[i] = o.newSlot(uint32(i), conn)
+	}
+
+	o.fitToSize()
+	o.notifyReleased()
+
+	go o.drain(old)
+	return nil
+}
+
+/*
+checkSize validates that the pool can be changed to hold size connections. The
+caller must hold the mutex.
+*/
+func (o *lb) checkSize(size uint32) error {
+	switch {
+	case o.closed:
+		return ErrClosed
+	case size <= 0:
+		return errors.New("size must be greater than 0")
+	case size > o.maxSize:
+		return fmt.Errorf("size %d exceeds the maximum of %d", size, o.maxSize)
+	case gcd(o.stride, size) != 1:
+		return errors.New("stride must be coprime with size")
+	case o.resetCall != nil:
+		return errors.New("reset already in progress")
+	}
+
+	return nil
+}
+
+/*
+drain closes the connections of slots that are no longer part of the pool once
+none of them has requests in flight, or right away when the pool is closed.
+With WithDrainTimeout the connections are closed once the timeout lapses even
+if requests are still in flight, and the affected slots are logged.
+*/
+func (o *lb) drain(slots []*slot) {
+	var timeout <-chan time.Time
+	if o.drainTimeout > 0 {
+		timer := time.NewTimer(o.drainTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		o.mutex.Lock()
+		var busy []uint32
+		for _, s := range slots {
+			if s.inFlight > 0 {
+				busy = append(busy, s.index)
+			}
+		}
+		released := o.released
+		o.unlock()
+
+		if len(busy) == 0 {
+			break
+		}
+
+		select {
+		case <-released:
+			continue
+		case <-o.done:
+			o.closeAll(connsOf(slots))
+			return
+		case <-timeout:
+		}
+
+		msg := "Drain timed out, closing connections with requests in flight:"
+		for _, index := range busy {
+			msg += " " + itoa(index)
+		}
+		o.log(msg)
+		break
+	}
+
+	for _, s := range slots {
+		if err := o.closeConn(s.conn); err != nil {
+			o.log("Failed to close removed connection: " + err.Error())
+		}
+	}
+}
+
+/*
+connsOf returns the connections of the given slots.
+*/
+func connsOf(slots []*slot) []*grpc.ClientConn {
+	conns := make([]*grpc.ClientConn, len(slots))
+	for i, s := range slots {
+		conns[i] = s.conn
+	}
+
+	return conns
+}
@@ -0,0 +1,107 @@
+package grpclb
+
+import "google.golang.org/grpc"
+
+/*
+WithTags assigns a set of tags to every connection, one entry per index, such
+as the zone or host its backend runs in. Tags drive GetAntiAffinity and are
+reported in Handle. Connections added by Resize get the tags of the last
+connection. New returns an error if the number of entries does not match the
+size.
+*/
+func WithTags(tags []map[string]string) Option {
+	return func(o *lb) {
+		o.tags = make([]map[string]string, len(tags))
+		for i, t := range tags {
+			o.tags[i] = copyTags(t)
+		}
+	}
+}
+
+/*
+copyTags returns a copy of tags, nil for nil.
+*/
+func copyTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(tags))
+	for k, v := range tags {
+		c[k] = v
+	}
+
+	return c
+}
+
+/*
+GetAntiAffinity selects a connection for the retry of a request whose previous
+attempt went to a connection tagged previousTags, typically taken from its
+Handle. Servable connections whose tags differ from previousTags on every key
+of it are preferred, so a retry after a failure in zone A lands in another
+zone, then servable connections differing on any key, then any servable
+connection and finally any connection. Without tags it behaves like Get.
+*/
+func (o *lb) GetAntiAffinity(previousTags map[string]string) *grpc.ClientConn {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	differsOnAll := func(s *slot) bool {
+		if o.tags == nil || len(previousTags) == 0 {
+			return false
+		}
+
+		for k, v := range previousTags {
+			if o.tags[s.index][k] == v {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	differsOnAny := func(s *slot) bool {
+		if o.tags == nil {
+			return false
+		}
+
+		for k, v := range previousTags {
+			if o.tags[s.index][k] != v {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	s, err := o.nextWith(nil, func(accept func(s *slot) bool) *slot {
+		for _, prefer := range []func(s *slot) bool{differsOnAll, differsOnAny} {
+			if s := o.pick(both(accept, both(prefer, o.servable))); s != nil {
+				return s
+			}
+		}
+
+		if s := o.pick(both(accept, o.servable)); s != nil {
+			return s
+		}
+
+		return o.pick(accept)
+	})
+	if err != nil || s == nil {
+		return nil
+	}
+
+	return s.conn
+}
+
+/*
+tagsOf returns a copy of the tags of the slot at index, nil without tags. The
+caller must hold the mutex.
+*/
+func (o *lb) tagsOf(index uint32) map[string]string {
+	if o.tags == nil {
+		return nil
+	}
+
+	return copyTags(o.tags[index])
+}
@@ -0,0 +1,78 @@
+package grpclb
+
+/*
+TieBreaker selects how the load aware strategies choose among connections
+with equal load.
+*/
+type TieBreaker int
+
+const (
+	// TieBreakRoundRobin takes the tied connections in turn, continuing from
+	// the round-robin offset. It is the default.
+	TieBreakRoundRobin TieBreaker = iota
+
+	// TieBreakRandom picks one of the tied connections uniformly at random,
+	// so that clients sharing the same view of the load do not pick in
+	// lockstep.
+	TieBreakRandom
+
+	// TieBreakLowestIndex picks the tied connection with the lowest index,
+	// which concentrates traffic but makes selection deterministic.
+	TieBreakLowestIndex
+)
+
+// tieBreakerNames maps the tie breaker names used in Config to tie breakers.
+var tieBreakerNames = map[string]TieBreaker{
+	"round_robin":  TieBreakRoundRobin,
+	"random":       TieBreakRandom,
+	"lowest_index": TieBreakLowestIndex,
+}
+
+/*
+WithTieBreaker sets how WeightedLeastConnections, LeastBytesInFlight, WithORCA
+and GetWithCost choose among connections with the same load or cost, which is
+common while a pool is warming up. The default is TieBreakRoundRobin.
+*/
+func WithTieBreaker(tieBreaker TieBreaker) Option {
+	return func(o *lb) {
+		o.tieBreaker = tieBreaker
+	}
+}
+
+/*
+breakTie picks one of the tied slots accepted by tied according to the tie
+breaker and counts the selection. It returns nil if no slot is accepted. The
+caller must hold the mutex.
+*/
+func (o *lb) breakTie(tied func(s *slot) bool) *slot {
+	var picked *slot
+	switch o.tieBreaker {
+	case TieBreakRandom:
+		n := 0
+		for _, s := range o.slots {
+			if !tied(s) {
+				continue
+			}
+
+			n++
+			if o.rand.Intn(n) == 0 {
+				picked = s
+			}
+		}
+	case TieBreakLowestIndex:
+		for _, s := range o.slots {
+			if tied(s) {
+				picked = s
+				break
+			}
+		}
+	default:
+		return o.scan(tied)
+	}
+
+	if picked != nil {
+		o.count(picked)
+	}
+
+	return picked
+}
@@ -0,0 +1,110 @@
+package grpclb
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+validate checks that the options combine into a working configuration and
+names what is missing or contradicting otherwise, so that a misconfiguration
+fails New instead of being silently ignored. It runs once the options have been
+applied, so it covers a Config passed to NewFromConfig as well.
+*/
+func (o *lb) validate() error {
+	if err := o.checkDialOptions(); err != nil {
+		return err
+	}
+
+	switch o.strategy {
+	case RoundRobin, LeastBytesInFlight:
+	case WeightedRandom, WeightedLeastConnections:
+		if o.weights != nil && !anyWeight(o.weights) {
+			return fmt.Errorf("strategy %s needs at least one connection with a non-zero weight", o.strategyName())
+		}
+	default:
+		return fmt.Errorf("unknown strategy %d", o.strategy)
+	}
+
+	switch o.tieBreaker {
+	case TieBreakRoundRobin, TieBreakRandom, TieBreakLowestIndex:
+	default:
+		return fmt.Errorf("unknown tie breaker %d", o.tieBreaker)
+	}
+
+	switch o.shrinkPolicy {
+	case ShrinkHighestIndex, ShrinkLeastUsed, ShrinkLeastHealthy:
+	default:
+		return fmt.Errorf("unknown shrink policy %d", o.shrinkPolicy)
+	}
+
+	switch {
+	case o.maxOverflow > 0 && o.maxInFlight == 0:
+		return errors.New("WithOverflow requires WithMaxInFlight: overflow connections are only dialed when every connection is at its in-flight limit")
+	case o.overflowIdleTimeout != 0 && o.maxOverflow == 0:
+		return errors.New("WithOverflow needs a maximum number of overflow connections for its idle timeout to apply")
+	case o.resetBatch == 0 && o.resetBatchDelay > 0:
+		return errors.New("WithResetBatch needs a batch size for its delay to apply")
+	case o.asyncReset && o.perSlotRecovery:
+		return errors.New("WithAsyncReset and WithPerSlotRecovery are mutually exclusive")
+	case !o.autoReset && o.asyncReset:
+		return errors.New("WithAsyncReset has no effect with automatic resets disabled by WithAutoReset")
+	case !o.autoReset && o.perSlotRecovery:
+		return errors.New("WithPerSlotRecovery has no effect with automatic resets disabled by WithAutoReset")
+	case o.maxConcurrentResets > 0 && !o.perSlotRecovery:
+		return errors.New("WithMaxConcurrentResets requires WithPerSlotRecovery")
+	case o.resetWait > 0 && !o.asyncReset:
+		return errors.New("WithResetWait requires WithAsyncReset")
+	case o.spillover < 0:
+		return fmt.Errorf("WithPrioritySpillover threshold %g must not be negative", o.spillover)
+	case o.spillover > 0 && o.priorities == nil:
+		return errors.New("WithPrioritySpillover requires WithPriorities")
+	case o.shadowFactory != nil && (o.shadowFraction < 0 || o.shadowFraction > 1):
+		return fmt.Errorf("WithShadow fraction %g must be in [0, 1]", o.shadowFraction)
+	case o.window == 0 && o.windowRotate > 0:
+		return errors.New("WithActiveWindow needs a window size for its rotation to apply")
+	case o.onCapacity != nil && (o.capacityThreshold <= 0 || o.capacityThreshold > 1):
+		return fmt.Errorf("WithCapacityAlert threshold %g must be in (0, 1]", o.capacityThreshold)
+	}
+
+	if cfg := o.outlier; cfg != nil {
+		switch {
+		case cfg.ConsecutiveFailures == 0 && cfg.MinSuccessRate == 0:
+			return errors.New("WithOutlierDetection needs ConsecutiveFailures or MinSuccessRate to eject anything")
+		case cfg.MinSuccessRate < 0 || cfg.MinSuccessRate > 1:
+			return fmt.Errorf("WithOutlierDetection MinSuccessRate %g must be in [0, 1]", cfg.MinSuccessRate)
+		case cfg.BaseEjectionTime <= 0:
+			return errors.New("WithOutlierDetection needs a positive BaseEjectionTime")
+		case cfg.MaxEjectionPercent > 100:
+			return fmt.Errorf("WithOutlierDetection MaxEjectionPercent %d exceeds 100", cfg.MaxEjectionPercent)
+		}
+	}
+
+	return nil
+}
+
+/*
+anyWeight reports whether any of the weights is not zero.
+*/
+func anyWeight(weights []uint32) bool {
+	for _, w := range weights {
+		if w > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+strategyName returns the configuration name of the pool's strategy.
+*/
+func (o *lb) strategyName() string {
+	for name, strategy := range strategyNames {
+		if strategy == o.strategy {
+			return name
+		}
+	}
+
+	return fmt.Sprint(int(o.strategy))
+}
@@ -0,0 +1,142 @@
+package grpclb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestValidateRejectsMisconfiguration(t *testing.T) {
+	disabled := false
+	factory := func() (*grpc.ClientConn, error) {
+		return nil, errors.New("validation must fail before dialing")
+	}
+
+	for _, c := range []struct {
+		name string
+		opts []Option
+		cfg  *Config
+		want string
+	}{
+		{
+			name: "weighted strategy with zero weights",
+			opts: []Option{WithStrategy(WeightedLeastConnections), WithWeights([]uint32{0, 0})},
+			cfg:  &Config{Strategy: "weighted_least_connections", Weights: []uint32{0, 0}},
+			want: "non-zero weight",
+		},
+		{
+			name: "unknown strategy",
+			opts: []Option{WithStrategy(Strategy(42))},
+			cfg:  &Config{Strategy: "fastest"},
+			want: "unknown strategy",
+		},
+		{
+			name: "overflow without in-flight limit",
+			opts: []Option{WithOverflow(2, 0)},
+			cfg:  &Config{MaxOverflow: 2},
+			want: "requires WithMaxInFlight",
+		},
+		{
+			name: "overflow idle timeout without overflow",
+			opts: []Option{WithOverflow(0, time.Second)},
+			cfg:  &Config{OverflowIdleTimeout: Duration(time.Second)},
+			want: "idle timeout",
+		},
+		{
+			name: "reset batch delay without batch",
+			opts: []Option{WithResetBatch(0, time.Second)},
+			cfg:  &Config{ResetBatchDelay: Duration(time.Second)},
+			want: "batch size",
+		},
+		{
+			name: "async reset with per slot recovery",
+			opts: []Option{WithAsyncReset(), WithPerSlotRecovery()},
+			cfg:  &Config{AsyncReset: true, PerSlotRecovery: true},
+			want: "mutually exclusive",
+		},
+		{
+			name: "async reset without automatic reset",
+			opts: []Option{WithAsyncReset(), WithAutoReset(false)},
+			cfg:  &Config{AsyncReset: true, AutoReset: &disabled},
+			want: "WithAsyncReset has no effect",
+		},
+		{
+			name: "per slot recovery without automatic reset",
+			opts: []Option{WithPerSlotRecovery(), WithAutoReset(false)},
+			cfg:  &Config{PerSlotRecovery: true, AutoReset: &disabled},
+			want: "WithPerSlotRecovery has no effect",
+		},
+		{
+			name: "concurrent reset limit without per slot recovery",
+			opts: []Option{WithMaxConcurrentResets(1)},
+			cfg:  &Config{MaxConcurrentResets: 1},
+			want: "requires WithPerSlotRecovery",
+		},
+		{
+			name: "reset wait without async reset",
+			opts: []Option{WithResetWait(time.Second)},
+			cfg:  &Config{ResetWait: Duration(time.Second)},
+			want: "requires WithAsyncReset",
+		},
+		{
+			name: "spillover without priorities",
+			opts: []Option{WithPrioritySpillover(0.5)},
+			cfg:  &Config{PrioritySpillover: 0.5},
+			want: "requires WithPriorities",
+		},
+		{
+			name: "window rotation without window",
+			opts: []Option{WithActiveWindow(0, time.Second)},
+			cfg:  &Config{ActiveWindowRotate: Duration(time.Second)},
+			want: "window size",
+		},
+		{
+			name: "outlier detection without thresholds",
+			opts: []Option{WithOutlierDetection(OutlierConfig{BaseEjectionTime: time.Second})},
+			cfg:  &Config{Outlier: &OutlierSetting{BaseEjectionTime: Duration(time.Second)}},
+			want: "to eject anything",
+		},
+		{
+			name: "outlier detection without ejection time",
+			opts: []Option{WithOutlierDetection(OutlierConfig{ConsecutiveFailures: 5})},
+			cfg:  &Config{Outlier: &OutlierSetting{ConsecutiveFailures: 5}},
+			want: "BaseEjectionTime",
+		},
+		{
+			name: "shadow fraction out of range",
+			opts: []Option{WithShadow(factory, 1.5)},
+			want: "must be in [0, 1]",
+		},
+		{
+			name: "capacity threshold out of range",
+			opts: []Option{WithCapacityAlert(0, func(float64) {})},
+			want: "must be in (0, 1]",
+		},
+		{
+			name: "dial options with a custom factory",
+			opts: []Option{WithDialOptions(grpc.WithUserAgent("test"))},
+			want: "require NewFromAddress",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := New(2, 1, factory, nil, c.opts...)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("New: got error %v, want one containing %q", err, c.want)
+			}
+
+			if c.cfg == nil {
+				return
+			}
+
+			cfg := *c.cfg
+			cfg.Size, cfg.MinRetryIntervalSeconds = 2, 1
+			_, err = NewFromConfig(cfg, factory, nil)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("NewFromConfig: got error %v, want one containing %q", err, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package grpclb
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+/*
+newSlot creates the slot at the given index holding conn. The caller must hold
+the mutex.
+*/
+func (o *lb) newSlot(index uint32, conn *grpc.ClientConn) *slot {
+	s := &slot{index: index}
+	o.attach(s, conn)
+	return s
+}
+
+/*
+attach installs conn as the connection of the slot, with fresh request
+statistics, and starts watching its connectivity state if a feature relies on
+it. The caller must hold the mutex.
+*/
+func (o *lb) attach(s *slot, conn *grpc.ClientConn) {
+	s.conn = conn
+	s.successes, s.failures, s.success = 0, 0, 1
+	s.consecutiveFailures, s.ejections, s.ejectedUntil = 0, 0, time.Time{}
+	s.cost = o.minCost()
+	s.durations, s.stateSince = nil, time.Time{}
+	s.usable, s.recoveredAt = false, time.Time{}
+	s.unservableSince, s.failing = time.Time{}, false
+	s.createdAt, s.proven = time.Now(), false
+	s.bytesInFlight, s.nudgedAt = 0, time.Time{}
+	s.utilization, s.reportedAt, s.readySince = 0, time.Time{}, time.Time{}
+	s.pickedAt = time.Time{}
+	s.lastState = connectivity.Idle
+	atomic.StoreUint64(&s.uses, 0)
+	o.bySlot[conn] = s
+	o.fastStale = true
+	if o.watchStates() {
+		o.startWatch(s)
+	}
+}
+
+/*
+startWatch starts watching the connectivity state of the slot's connection. The
+caller must hold the mutex.
+*/
+func (o *lb) startWatch(s *slot) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopWatch = cancel
+	go o.watch(ctx, s, s.conn)
+}
+
+/*
+detach stops watching the slot's current connection and removes the slot from
+the ready set and the connection lookup. The connection itself is left open.
+The caller must hold the mutex.
+*/
+func (o *lb) detach(s *slot) {
+	delete(o.bySlot, s.conn)
+	o.invalidate()
+
+	if s.stopWatch != nil {
+		s.stopWatch()
+		s.stopWatch = nil
+	}
+
+	o.removeReady(s)
+}
+
+/*
+watchStates reports whether any enabled feature needs the state watcher.
+*/
+func (o *lb) watchStates() bool {
+	return o.readySet || o.stateDurations || o.preferRecovered > 0 || o.onCapacity != nil ||
+		o.trackReady || o.goAwayRefresh || o.events != nil || o.slowStart > 0
+}
+
+/*
+watch follows the connectivity state of conn until ctx is cancelled, recording
+every transition on the slot.
+*/
+func (o *lb) watch(ctx context.Context, s *slot, conn *grpc.ClientConn) {
+	for {
+		state := conn.GetState()
+		o.stateChanged(s, conn, state)
+
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+/*
+stateChanged records that the slot's connection moved to state. Transitions of
+a connection that has been replaced in the meantime are ignored.
+*/
+func (o *lb) stateChanged(s *slot, conn *grpc.ClientConn, state connectivity.State) {
+	o.mutex.Lock()
+	defer o.unlock()
+
+	if s.conn != conn || s.stopWatch == nil {
+		return
+	}
+
+	o.emitState(s, state)
+
+	if o.readySet {
+		if o.usable(state) {
+			o.addReady(s)
+		} else {
+			o.removeReady(s)
+		}
+	}
+
+	if o.stateDurations {
+		o.recordState(s, state, time.Now())
+	}
+
+	if o.preferRecovered > 0 {
+		o.recordRecovery(s, o.usable(state))
+	}
+
+	if o.onCapacity != nil {
+		trackFailing(s, state)
+		o.checkCapacity()
+	}
+
+	if o.slowStart > 0 {
+		trackWarmup(s, state)
+	}
+
+	if o.trackReady && state == connectivity.Ready {
+		o.markReady()
+	}
+
+	if o.goAwayRefresh {
+		o.goneAway(s, state)
+	}
+}
@@ -0,0 +1,60 @@
+package grpclb
+
+import "time"
+
+/*
+WithActiveWindow keeps only a window of w consecutive connections in rotation,
+for large pools where only a handful of connections should carry traffic at a
+time. The window advances by one connection every rotateEvery, so every
+connection is eventually exercised and stays warm. Servable connections in the
+window are preferred, then servable ones outside of it, so an unhealthy window
+does not stall selection. A window at least as large as the pool has no
+effect.
+*/
+func WithActiveWindow(w uint32, rotateEvery time.Duration) Option {
+	return func(o *lb) {
+		o.window, o.windowRotate, o.windowEpoch = w, rotateEvery, time.Now()
+	}
+}
+
+/*
+windowed reports whether selection is restricted to an active window. The
+caller must hold the mutex.
+*/
+func (o *lb) windowed() bool {
+	return o.window > 0 && o.window < o.size
+}
+
+/*
+inWindow returns a filter accepting the slots in the current active window.
+The caller must hold the mutex.
+*/
+func (o *lb) inWindow() func(s *slot) bool {
+	var start uint64
+	if o.windowRotate > 0 {
+		start = uint64(time.Since(o.windowEpoch)/o.windowRotate) % uint64(o.size)
+	}
+
+	return func(s *slot) bool {
+		return (uint64(s.index)+uint64(o.size)-start)%uint64(o.size) < uint64(o.window)
+	}
+}
+
+/*
+pickWindowed wraps pick so that it prefers servable slots in the active window,
+then any servable slot, then any slot. The caller must hold the mutex.
+*/
+func (o *lb) pickWindowed(pick func(accept func(s *slot) bool) *slot) func(accept func(s *slot) bool) *slot {
+	inWindow := o.inWindow()
+	return func(accept func(s *slot) bool) *slot {
+		if s := pick(both(accept, both(inWindow, o.servable))); s != nil {
+			return s
+		}
+
+		if s := pick(both(accept, o.servable)); s != nil {
+			return s
+		}
+
+		return pick(accept)
+	}
+}